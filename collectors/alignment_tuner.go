@@ -0,0 +1,94 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sync"
+	"time"
+)
+
+// minAlignmentPeriod is the smallest alignment period accepted by the Monitoring API.
+const minAlignmentPeriod = time.Minute
+
+// alignmentTuner increases the alignment period of the metric types whose time series return more points than
+// allowed, so that the points requested per series stay bounded across scrapes. A nil alignmentTuner never aligns.
+type alignmentTuner struct {
+	maxPoints int
+	interval  time.Duration
+
+	mu      sync.Mutex
+	periods map[string]time.Duration
+}
+
+func newAlignmentTuner(maxPoints int, interval time.Duration) *alignmentTuner {
+	if maxPoints <= 0 {
+		return nil
+	}
+	return &alignmentTuner{
+		maxPoints: maxPoints,
+		interval:  interval,
+		periods:   make(map[string]time.Duration),
+	}
+}
+
+// period returns the alignment period of a metric type, zero if its time series are not aligned.
+func (a *alignmentTuner) period(metricType string) time.Duration {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.periods[metricType]
+}
+
+// observe records the largest number of points returned for a time series of a metric type. When it exceeds the
+// maximum the alignment period is increased, up to the request interval, and the new period is returned.
+func (a *alignmentTuner) observe(metricType string, points int) (time.Duration, bool) {
+	if a == nil || points <= a.maxPoints {
+		return 0, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := a.periods[metricType]
+	period := roundUpToMinute(a.interval / time.Duration(a.maxPoints))
+	// The points are not evenly spread or the period was already derived from the interval
+	if period <= current {
+		period = 2 * current
+	}
+	period = min(period, roundUpToMinute(a.interval))
+	if period <= current {
+		return current, false
+	}
+	a.periods[metricType] = period
+	return period, true
+}
+
+// tunableAligner returns whether the alignment tuner may align the time series of a metric kind and value type with a
+// per series aligner: the API must accept the aligner and the time series must keep their metric kind and value type.
+// No aligner satisfies both for CUMULATIVE metrics and GAUGE distributions, they are never tuned.
+func tunableAligner(metricKind, valueType, aligner string) bool {
+	switch metricKind {
+	case "GAUGE":
+		return (valueType == "INT64" || valueType == "DOUBLE") && (aligner == "ALIGN_MEAN" || aligner == "ALIGN_MIN" || aligner == "ALIGN_MAX")
+	case "DELTA":
+		return (valueType == "INT64" || valueType == "DOUBLE" || valueType == "DISTRIBUTION") && (aligner == "ALIGN_DELTA" || aligner == "ALIGN_SUM")
+	}
+	return false
+}
+
+// roundUpToMinute rounds a duration up to a whole number of minutes, at least the minimum alignment period.
+func roundUpToMinute(d time.Duration) time.Duration {
+	return max((d + time.Minute - 1).Truncate(time.Minute), minAlignmentPeriod)
+}
@@ -0,0 +1,240 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestAlignmentTunerObserve(t *testing.T) {
+	tuner := newAlignmentTuner(10, 30*time.Minute)
+
+	if _, increased := tuner.observe("custom.googleapis.com/a", 10); increased {
+		t.Error("Expected the alignment period to stay unset at the maximum points")
+	}
+
+	// 30 minutes in 10 points of 3 minutes, then doubled while there are still too many points
+	for _, expected := range []time.Duration{3 * time.Minute, 6 * time.Minute, 12 * time.Minute, 24 * time.Minute, 30 * time.Minute} {
+		period, increased := tuner.observe("custom.googleapis.com/a", 11)
+		if !increased || period != expected {
+			t.Errorf("Expected the alignment period to increase to %v, got %v (increased %v)", expected, period, increased)
+		}
+	}
+	if period, increased := tuner.observe("custom.googleapis.com/a", 11); increased || period != 30*time.Minute {
+		t.Errorf("Expected the alignment period to be capped to the interval, got %v (increased %v)", period, increased)
+	}
+
+	if got := tuner.period("custom.googleapis.com/b"); got != 0 {
+		t.Errorf("Expected no alignment period for another metric type, got %v", got)
+	}
+
+	// Short intervals are aligned on the minimum alignment period
+	short := newAlignmentTuner(10, 5*time.Minute)
+	if period, _ := short.observe("custom.googleapis.com/a", 20); period != minAlignmentPeriod {
+		t.Errorf("Expected the minimum alignment period, got %v", period)
+	}
+
+	var disabled *alignmentTuner
+	if _, increased := disabled.observe("custom.googleapis.com/a", 100); increased || disabled.period("custom.googleapis.com/a") != 0 {
+		t.Error("Expected a nil tuner to never align")
+	}
+}
+
+func TestMaxPointsPerSeries(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	now := time.Now()
+	timeSeries := newTestTimeSeries(metricType, "GAUGE", 0, now.Add(-20*time.Minute))
+	for i := 19; i >= 0; i-- {
+		value := float64(i)
+		timeSeries.Points = append(timeSeries.Points, &monitoring.Point{
+			Interval: &monitoring.TimeInterval{EndTime: now.Add(-time.Duration(i) * time.Minute).Format(time.RFC3339Nano)},
+			Value:    &monitoring.TypedValue{DoubleValue: &value},
+		})
+	}
+
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor(metricType, "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/flag", "GAUGE", "BOOL"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: []*monitoring.TimeSeries{timeSeries}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    20 * time.Minute,
+		MaxPointsPerSeries: 5,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	alignments := func() map[string][2]string {
+		alignments := make(map[string][2]string)
+		for _, request := range api.timeSeriesRequests {
+			if m := fakeMetricTypeRE.FindStringSubmatch(request.Get("filter")); m != nil {
+				alignments[m[1]] = [2]string{request.Get("aggregation.alignmentPeriod"), request.Get("aggregation.perSeriesAligner")}
+			}
+		}
+		api.timeSeriesRequests = nil
+		return alignments
+	}
+
+	gatherFamilies(t, collector)
+	if got := alignments()[metricType]; got != [2]string{} {
+		t.Errorf("Expected the first scrape not to be aligned, got %v", got)
+	}
+
+	// 21 points in the first scrape, 20 minutes in 5 points of 4 minutes
+	gatherFamilies(t, collector)
+	got := alignments()
+	if expected := [2]string{"240s", "ALIGN_MEAN"}; got[metricType] != expected {
+		t.Errorf("Expected the alignment %v, got %v", expected, got[metricType])
+	}
+	// The value types without aligner are never aligned
+	if got["custom.googleapis.com/flag"] != [2]string{} {
+		t.Errorf("Expected the BOOL metric type not to be aligned, got %v", got["custom.googleapis.com/flag"])
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:    5 * time.Minute,
+		MaxPointsPerSeries: -1,
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for a negative max points per series")
+	}
+}
+
+func TestMaxPointsPerSeriesMetricKinds(t *testing.T) {
+	now := time.Now()
+	withPoints := func(timeSeries *monitoring.TimeSeries) *monitoring.TimeSeries {
+		value := timeSeries.Points[0].Value
+		for i := 1; i <= 20; i++ {
+			timeSeries.Points = append(timeSeries.Points, &monitoring.Point{
+				Interval: &monitoring.TimeInterval{EndTime: now.Add(-time.Duration(i) * time.Minute).Format(time.RFC3339Nano)},
+				Value:    value,
+			})
+		}
+		return timeSeries
+	}
+	newDistribution := func() *monitoring.Distribution {
+		return &monitoring.Distribution{
+			Count:         1,
+			Mean:          1,
+			BucketCounts:  googleapi.Int64s{0, 1},
+			BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{Bounds: []float64{0.5}}},
+		}
+	}
+
+	descriptors := []*monitoring.MetricDescriptor{
+		newTestDescriptor("custom.googleapis.com/gauge", "GAUGE", "DOUBLE"),
+		newTestDescriptor("custom.googleapis.com/cumulative", "CUMULATIVE", "DOUBLE"),
+		newTestDescriptor("custom.googleapis.com/gauge_distribution", "GAUGE", "DISTRIBUTION"),
+		newTestDescriptor("custom.googleapis.com/cumulative_distribution", "CUMULATIVE", "DISTRIBUTION"),
+		newTestDescriptor("custom.googleapis.com/delta_distribution", "DELTA", "DISTRIBUTION"),
+	}
+	timeSeries := map[string][]*monitoring.ListTimeSeriesResponse{}
+	for _, descriptor := range descriptors {
+		series := newTestTimeSeries(descriptor.Type, descriptor.MetricKind, 1, now)
+		if descriptor.ValueType == "DISTRIBUTION" {
+			series = newTestDistributionTimeSeries(descriptor.Type, descriptor.MetricKind, newDistribution(), now)
+		}
+		timeSeries[descriptor.Type] = []*monitoring.ListTimeSeriesResponse{{TimeSeries: []*monitoring.TimeSeries{withPoints(series)}}}
+	}
+
+	tests := []struct {
+		name     string
+		aligners map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "default aligners",
+			expected: map[string]string{
+				"custom.googleapis.com/gauge":              "ALIGN_MEAN",
+				"custom.googleapis.com/delta_distribution": "ALIGN_DELTA",
+			},
+		},
+		{
+			// Aligners by value type apply to every metric kind, only the valid ones are used by the tuner
+			name:     "aligners by value type",
+			aligners: map[string]string{"DOUBLE": "ALIGN_MEAN", "DISTRIBUTION": "ALIGN_DELTA"},
+			expected: map[string]string{
+				"custom.googleapis.com/gauge":              "ALIGN_MEAN",
+				"custom.googleapis.com/delta_distribution": "ALIGN_DELTA",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeMonitoringAPI{descriptors: descriptors, timeSeries: timeSeries}
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    20 * time.Minute,
+				MaxPointsPerSeries: 5,
+				ValueTypeAligners:  tt.aligners,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			gatherFamilies(t, collector)
+			api.timeSeriesRequests = nil
+			gatherFamilies(t, collector)
+
+			aligners := make(map[string]string)
+			for _, request := range api.timeSeriesRequests {
+				m := fakeMetricTypeRE.FindStringSubmatch(request.Get("filter"))
+				if aligner := request.Get("aggregation.perSeriesAligner"); m != nil && aligner != "" {
+					aligners[m[1]] = aligner
+				}
+			}
+			if !reflect.DeepEqual(aligners, tt.expected) {
+				t.Errorf("Expected tuned aligners %v, got %v", tt.expected, aligners)
+			}
+		})
+	}
+}
+
+func TestTunableAligner(t *testing.T) {
+	tests := []struct {
+		metricKind, valueType, aligner string
+		expected                       bool
+	}{
+		{metricKind: "GAUGE", valueType: "DOUBLE", aligner: "ALIGN_MEAN", expected: true},
+		{metricKind: "GAUGE", valueType: "INT64", aligner: "ALIGN_MAX", expected: true},
+		{metricKind: "GAUGE", valueType: "DOUBLE", aligner: "ALIGN_DELTA", expected: false},
+		{metricKind: "GAUGE", valueType: "DISTRIBUTION", aligner: "ALIGN_DELTA", expected: false},
+		{metricKind: "GAUGE", valueType: "DISTRIBUTION", aligner: "ALIGN_MEAN", expected: false},
+		{metricKind: "GAUGE", valueType: "BOOL", aligner: "ALIGN_MEAN", expected: false},
+		{metricKind: "DELTA", valueType: "DISTRIBUTION", aligner: "ALIGN_DELTA", expected: true},
+		{metricKind: "DELTA", valueType: "INT64", aligner: "ALIGN_MEAN", expected: false},
+		{metricKind: "CUMULATIVE", valueType: "DOUBLE", aligner: "ALIGN_MEAN", expected: false},
+		{metricKind: "CUMULATIVE", valueType: "DISTRIBUTION", aligner: "ALIGN_DELTA", expected: false},
+		{metricKind: "CUMULATIVE", valueType: "INT64", aligner: "ALIGN_RATE", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := tunableAligner(tt.metricKind, tt.valueType, tt.aligner); got != tt.expected {
+			t.Errorf("tunableAligner(%s, %s, %s) = %v, want %v", tt.metricKind, tt.valueType, tt.aligner, got, tt.expected)
+		}
+	}
+}
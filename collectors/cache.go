@@ -65,6 +65,19 @@ func (d *descriptorCache) Lookup(prefix string) []*monitoring.MetricDescriptor {
 	return v.data
 }
 
+// LookupStale returns a list of MetricDescriptors if the prefix is found even if expired, and whether it has expired
+func (d *descriptorCache) LookupStale(prefix string) ([]*monitoring.MetricDescriptor, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	v, ok := d.cache[prefix]
+	if !ok {
+		return nil, false
+	}
+
+	return v.data, time.Now().After(v.expiry)
+}
+
 // Store overrides a cache entry
 func (d *descriptorCache) Store(prefix string, data []*monitoring.MetricDescriptor) {
 	entry := descriptorCacheEntry{data: data, expiry: time.Now().Add(d.ttl)}
@@ -0,0 +1,136 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus-community/stackdriver_exporter/hash"
+)
+
+// configHasher hashes the resolved scrape configuration of a collector. Each value is followed by a separator and
+// each list by its length, so that values moving from one list to the next change the hash.
+type configHasher struct {
+	h uint64
+}
+
+func (c *configHasher) add(values ...string) {
+	for _, value := range values {
+		c.h = hash.Add(c.h, value)
+		c.h = hash.AddByte(c.h, hash.SeparatorByte)
+	}
+}
+
+func (c *configHasher) addList(values []string) {
+	c.add(values...)
+	c.add(strconv.Itoa(len(values)))
+}
+
+func (c *configHasher) addMap(values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.add(key, values[key])
+	}
+	c.add(strconv.Itoa(len(values)))
+}
+
+func (c *configHasher) addAggregation(config MetricAggregationConfig) {
+	c.add(config.TargetedMetricPrefix, config.AlignmentPeriod, config.CrossSeriesReducer, config.PerSeriesAligner)
+	c.addList(config.GroupByFields)
+}
+
+// configHash returns a stable hash of the resolved scrape configuration, including the configuration loaded from the
+// scrape config file: what is requested from the API and how it is reported. It is truncated to 53 bits to be exactly
+// represented by a gauge. The options which only change the internal metrics, the logs, the caches or the concurrency
+// are not included.
+func (c *MonitoringCollector) configHash(config *ScrapeConfig) uint64 {
+	hasher := &configHasher{h: hash.New()}
+
+	hasher.addList(c.metricsTypePrefixes)
+	for _, target := range c.metricTargets {
+		hasher.add(target.ProjectID, target.MetricType, target.MetricKind, target.ValueType)
+		if target.Aggregation != nil {
+			hasher.addAggregation(*target.Aggregation)
+		}
+	}
+	hasher.add(strconv.Itoa(len(c.metricTargets)))
+
+	hasher.add(c.descriptorPredicate.MaxSamplePeriod.String())
+	hasher.addList(c.descriptorPredicate.LaunchStages)
+	hasher.addList(c.descriptorPredicate.MetricNames)
+
+	for _, filter := range c.metricsFilters {
+		hasher.add(filter.TargetedMetricPrefix, filter.FilterQuery)
+	}
+	hasher.add(strconv.Itoa(len(c.metricsFilters)))
+	hasher.addMap(c.resourceLabelFilters)
+
+	for _, config := range c.metricsAggregationConfigs {
+		hasher.addAggregation(config)
+	}
+	hasher.add(strconv.Itoa(len(c.metricsAggregationConfigs)))
+	for _, config := range config.Aggregations {
+		hasher.addAggregation(config)
+	}
+	hasher.add(strconv.Itoa(len(config.Aggregations)))
+	for _, filter := range config.ExtraFilters {
+		hasher.add(filter.TargetedMetricPrefix, filter.FilterQuery)
+	}
+	hasher.add(strconv.Itoa(len(config.ExtraFilters)))
+	hasher.addMap(c.valueTypeAligners)
+
+	policy := make(map[string]string, len(c.metricTypePolicy))
+	for key, valueType := range c.metricTypePolicy {
+		policy[fmt.Sprintf("%s:%t", key.MetricKind, key.AggregateDeltas)] = strconv.Itoa(int(valueType))
+	}
+	hasher.addMap(policy)
+
+	hasher.add(
+		c.metricsInterval.String(),
+		c.metricsOffset.String(),
+		strconv.FormatBool(c.metricsIngestDelay),
+		strconv.FormatBool(c.collectorFillMissingLabels),
+		strconv.FormatBool(c.monitoringDropDelegatedProjects),
+		strconv.FormatBool(c.aggregateDeltas),
+		strconv.FormatBool(c.nameSuffixes),
+		strconv.FormatBool(c.resourceLabelNames != nil),
+		strconv.FormatBool(c.nativeHistograms),
+		strconv.FormatBool(c.createdTimestamps),
+		strconv.FormatBool(c.strictExplicitBuckets),
+		strconv.FormatBool(c.lastSeenMetrics),
+		strconv.FormatBool(c.distributionFallback),
+		strconv.FormatBool(c.invalidPointsTotalMetric != nil),
+		strconv.FormatBool(c.missingDescriptorsTotalMetric != nil),
+		c.futurePoints,
+		c.newestPointTies,
+		c.unitConflicts,
+		c.scrapeGuard.policy,
+	)
+	if c.alignmentTuner != nil {
+		hasher.add(strconv.Itoa(c.alignmentTuner.maxPoints))
+	}
+	hasher.addList(c.deltaCounterPrefixes)
+	hasher.addList(c.gaugeCounterPrefixes)
+	if c.gaugeCounters != nil {
+		hasher.add(c.gaugeCounters.ttl.String())
+	}
+
+	return hasher.h & (1<<53 - 1)
+}
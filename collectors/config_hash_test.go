@@ -0,0 +1,239 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestConfigHash(t *testing.T) {
+	newOpts := func() MonitoringCollectorOptions {
+		return MonitoringCollectorOptions{
+			MetricTypePrefixes:   []string{"compute.googleapis.com/instance", "custom.googleapis.com"},
+			ExtraFilters:         []MetricFilter{{TargetedMetricPrefix: "compute.googleapis.com", FilterQuery: `resource.labels.zone = "us-east1-b"`}},
+			ResourceLabelFilters: map[string]string{"env": "prod", "team": "infra"},
+			MetricAggregationConfigs: []MetricAggregationConfig{
+				{TargetedMetricPrefix: "custom.googleapis.com", AlignmentPeriod: "60s", CrossSeriesReducer: "REDUCE_SUM"},
+			},
+			RequestInterval:  5 * time.Minute,
+			ConfigHashMetric: true,
+		}
+	}
+
+	configHash := func(opts MonitoringCollectorOptions) float64 {
+		t.Helper()
+		collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, opts, slog.Default(), nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create collector: %v", err)
+		}
+		return testutil.ToFloat64(collector.configHashMetric)
+	}
+
+	hash := configHash(newOpts())
+	if hash == 0 {
+		t.Fatal("Expected a non-zero config hash")
+	}
+	if got := configHash(newOpts()); got != hash {
+		t.Errorf("Expected the config hash %v to be stable, got %v", hash, got)
+	}
+
+	changes := map[string]func(opts *MonitoringCollectorOptions){
+		"prefixes": func(opts *MonitoringCollectorOptions) {
+			opts.MetricTypePrefixes = append(opts.MetricTypePrefixes, "pubsub.googleapis.com")
+		},
+		"moved prefix": func(opts *MonitoringCollectorOptions) {
+			opts.MetricTypePrefixes = opts.MetricTypePrefixes[:1]
+			opts.ExplicitTargets = []MetricTarget{{MetricType: "custom.googleapis.com"}}
+		},
+		"extra filters": func(opts *MonitoringCollectorOptions) {
+			opts.ExtraFilters[0].FilterQuery = `resource.labels.zone = "us-east1-c"`
+		},
+		"resource label filters": func(opts *MonitoringCollectorOptions) {
+			opts.ResourceLabelFilters["env"] = "dev"
+		},
+		"aggregations": func(opts *MonitoringCollectorOptions) {
+			opts.MetricAggregationConfigs[0].AlignmentPeriod = "120s"
+		},
+		"interval": func(opts *MonitoringCollectorOptions) {
+			opts.RequestInterval = 10 * time.Minute
+		},
+		"metric names": func(opts *MonitoringCollectorOptions) {
+			opts.DescriptorPredicate.MetricNames = []string{"stackdriver_gce_instance_compute_googleapis_com_instance_uptime"}
+		},
+		"aggregate deltas": func(opts *MonitoringCollectorOptions) {
+			opts.AggregateDeltas = true
+		},
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			opts := newOpts()
+			change(&opts)
+			if got := configHash(opts); got == hash {
+				t.Errorf("Expected the config hash to change, got %v", got)
+			}
+		})
+	}
+}
+
+// configHashExcludedOptions are the options which do not change the reported metrics: the internal metrics, the logs,
+// the caches and the concurrency. Every other option must change the config hash.
+var configHashExcludedOptions = []string{
+	"SeparateInternalMetrics",
+	"DescriptorCacheTTL",
+	"DescriptorCacheOnlyGoogle",
+	"DescriptorCacheBackgroundRefresh",
+	"DescriptorScrapeErrors",
+	"APICallsLastScrape",
+	"MaxConcurrency",
+	"PrefixConcurrency",
+	"DescriptorProfileMetrics",
+	"ConfigHashMetric",
+	"ScrapeSummaryLog",
+	"PostScrapeHook",
+	"StartupValidatePrefixes",
+	"OldestAPICallMetric",
+	"LabelLayoutCache",
+}
+
+func TestConfigHashOptions(t *testing.T) {
+	scrapeConfigPath := filepath.Join(t.TempDir(), "scrape_config.yml")
+	if err := os.WriteFile(scrapeConfigPath, []byte("aggregations:\n  - targeted_metric_prefix: custom.googleapis.com\n    alignment_period: 60s\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write scrape config: %v", err)
+	}
+	scrapeConfigFile, err := NewScrapeConfigFile(scrapeConfigPath)
+	if err != nil {
+		t.Fatalf("Failed to load scrape config: %v", err)
+	}
+
+	newOpts := func() MonitoringCollectorOptions {
+		return MonitoringCollectorOptions{
+			MetricTypePrefixes:   []string{"custom.googleapis.com"},
+			RequestInterval:      5 * time.Minute,
+			GaugeCounterPrefixes: []string{"custom.googleapis.com/processed"},
+			GaugeCounterTTL:      time.Hour,
+			ConfigHashMetric:     true,
+		}
+	}
+
+	// The changes are keyed by option name, the fields of a struct option by <option>.<field>
+	changes := map[string]func(opts *MonitoringCollectorOptions){
+		"MetricTypePrefixes": func(opts *MonitoringCollectorOptions) {
+			opts.MetricTypePrefixes = append(opts.MetricTypePrefixes, "pubsub.googleapis.com")
+		},
+		"ExplicitTargets": func(opts *MonitoringCollectorOptions) {
+			opts.ExplicitTargets = []MetricTarget{{MetricType: "pubsub.googleapis.com/subscription/num_undelivered_messages"}}
+		},
+		"DescriptorPredicate.MaxSamplePeriod": func(opts *MonitoringCollectorOptions) {
+			opts.DescriptorPredicate.MaxSamplePeriod = time.Minute
+		},
+		"DescriptorPredicate.LaunchStages": func(opts *MonitoringCollectorOptions) {
+			opts.DescriptorPredicate.LaunchStages = []string{"GA"}
+		},
+		"DescriptorPredicate.MetricNames": func(opts *MonitoringCollectorOptions) {
+			opts.DescriptorPredicate.MetricNames = []string{"stackdriver_gce_instance_custom_googleapis_com_a"}
+		},
+		"ExtraFilters": func(opts *MonitoringCollectorOptions) {
+			opts.ExtraFilters = []MetricFilter{{TargetedMetricPrefix: "custom.googleapis.com", FilterQuery: `resource.labels.zone = "us-east1-b"`}}
+		},
+		"ResourceLabelFilters": func(opts *MonitoringCollectorOptions) {
+			opts.ResourceLabelFilters = map[string]string{"env": "prod"}
+		},
+		"MetricAggregationConfigs": func(opts *MonitoringCollectorOptions) {
+			opts.MetricAggregationConfigs = []MetricAggregationConfig{{TargetedMetricPrefix: "custom.googleapis.com", AlignmentPeriod: "60s"}}
+		},
+		"RequestInterval":       func(opts *MonitoringCollectorOptions) { opts.RequestInterval = 10 * time.Minute },
+		"RequestOffset":         func(opts *MonitoringCollectorOptions) { opts.RequestOffset = time.Minute },
+		"IngestDelay":           func(opts *MonitoringCollectorOptions) { opts.IngestDelay = true },
+		"FillMissingLabels":     func(opts *MonitoringCollectorOptions) { opts.FillMissingLabels = true },
+		"DropDelegatedProjects": func(opts *MonitoringCollectorOptions) { opts.DropDelegatedProjects = true },
+		"AggregateDeltas":       func(opts *MonitoringCollectorOptions) { opts.AggregateDeltas = true },
+		"CreatedTimestamps":     func(opts *MonitoringCollectorOptions) { opts.CreatedTimestamps = true },
+		"MetricTypePolicy": func(opts *MonitoringCollectorOptions) {
+			opts.MetricTypePolicy = DefaultMetricTypePolicy()
+			opts.MetricTypePolicy[MetricTypeKey{MetricKind: "GAUGE"}] = prometheus.UntypedValue
+		},
+		"StrictExplicitBuckets": func(opts *MonitoringCollectorOptions) { opts.StrictExplicitBuckets = true },
+		"LastSeenMetrics":       func(opts *MonitoringCollectorOptions) { opts.LastSeenMetrics = true },
+		"FuturePoints":          func(opts *MonitoringCollectorOptions) { opts.FuturePoints = FuturePointsDrop },
+		"NewestPointTies":       func(opts *MonitoringCollectorOptions) { opts.NewestPointTies = NewestPointLast },
+		"UnitConflicts":         func(opts *MonitoringCollectorOptions) { opts.UnitConflicts = UnitConflictsDrop },
+		"DistributionFallback":  func(opts *MonitoringCollectorOptions) { opts.DistributionFallback = true },
+		"GaugeCounterPrefixes": func(opts *MonitoringCollectorOptions) {
+			opts.GaugeCounterPrefixes = []string{"custom.googleapis.com/other"}
+		},
+		"GaugeCounterTTL":        func(opts *MonitoringCollectorOptions) { opts.GaugeCounterTTL = 2 * time.Hour },
+		"SkipInvalidPoints":      func(opts *MonitoringCollectorOptions) { opts.SkipInvalidPoints = true },
+		"SkipMissingDescriptors": func(opts *MonitoringCollectorOptions) { opts.SkipMissingDescriptors = true },
+		"ValueTypeAligners": func(opts *MonitoringCollectorOptions) {
+			opts.ValueTypeAligners = map[string]string{"DOUBLE": "ALIGN_MAX"}
+		},
+		"DeltaCounterPrefixes":  func(opts *MonitoringCollectorOptions) { opts.DeltaCounterPrefixes = []string{} },
+		"OverlappingScrapes":    func(opts *MonitoringCollectorOptions) { opts.OverlappingScrapes = OverlappingScrapesReject },
+		"ScrapeConfigFile":      func(opts *MonitoringCollectorOptions) { opts.ScrapeConfigFile = scrapeConfigFile },
+		"NameSuffixes":          func(opts *MonitoringCollectorOptions) { opts.NameSuffixes = true },
+		"MaxPointsPerSeries":    func(opts *MonitoringCollectorOptions) { opts.MaxPointsPerSeries = 10 },
+		"ResourceDisplayLabels": func(opts *MonitoringCollectorOptions) { opts.ResourceDisplayLabels = true },
+		"NativeHistograms":      func(opts *MonitoringCollectorOptions) { opts.NativeHistograms = true },
+	}
+
+	// A new option must either change the config hash or be explicitly excluded
+	optionsType := reflect.TypeOf(MonitoringCollectorOptions{})
+	for i := 0; i < optionsType.NumField(); i++ {
+		field := optionsType.Field(i)
+		names := []string{field.Name}
+		if field.Type.Kind() == reflect.Struct {
+			names = nil
+			for j := 0; j < field.Type.NumField(); j++ {
+				names = append(names, field.Name+"."+field.Type.Field(j).Name)
+			}
+		}
+		for _, name := range names {
+			_, changed := changes[name]
+			excluded := slices.Contains(configHashExcludedOptions, name)
+			if changed == excluded {
+				t.Errorf("Option %s must either be part of the config hash or be excluded from it", name)
+			}
+		}
+	}
+
+	configHash := func(opts MonitoringCollectorOptions) float64 {
+		t.Helper()
+		collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, opts, slog.Default(), nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create collector: %v", err)
+		}
+		return testutil.ToFloat64(collector.configHashMetric)
+	}
+
+	hash := configHash(newOpts())
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			opts := newOpts()
+			change(&opts)
+			if got := configHash(opts); got == hash {
+				t.Errorf("Expected the config hash to change, got %v", got)
+			}
+		})
+	}
+}
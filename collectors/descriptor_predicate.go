@@ -0,0 +1,101 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/utils"
+)
+
+var metricNameRE = regexp.MustCompile(`\b` + namespace + `_[a-zA-Z0-9_]+`)
+
+// DescriptorPredicate selects the listed metric descriptors from their metadata. Every condition which is set must be
+// satisfied, the zero value matches all the descriptors.
+type DescriptorPredicate struct {
+	// MaxSamplePeriod only matches the descriptors sampled at least this often. Descriptors without a sample period do
+	// not match.
+	MaxSamplePeriod time.Duration
+	// LaunchStages only matches the descriptors with one of these launch stages, i.e. GA or BETA.
+	LaunchStages []string
+	// MetricNames only matches the descriptors of the metric types reported with one of these Prometheus metric names,
+	// whatever the monitored resource type and the suffix (`_count`, `_total`...). Names which do not match any metric
+	// type are ignored.
+	MetricNames []string
+}
+
+// Matches returns whether a metric descriptor satisfies the predicate.
+func (p DescriptorPredicate) Matches(descriptor *monitoring.MetricDescriptor) bool {
+	if p.MaxSamplePeriod > 0 {
+		if descriptor.Metadata == nil || descriptor.Metadata.SamplePeriod == "" {
+			return false
+		}
+		samplePeriod, err := time.ParseDuration(descriptor.Metadata.SamplePeriod)
+		if err != nil || samplePeriod > p.MaxSamplePeriod {
+			return false
+		}
+	}
+
+	if len(p.LaunchStages) > 0 {
+		launchStage := descriptor.LaunchStage
+		if launchStage == "" && descriptor.Metadata != nil {
+			launchStage = descriptor.Metadata.LaunchStage
+		}
+		if !slices.Contains(p.LaunchStages, launchStage) {
+			return false
+		}
+	}
+
+	if len(p.MetricNames) > 0 && !slices.ContainsFunc(p.MetricNames, func(name string) bool {
+		return metricNameReports(name, descriptor.Type)
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// metricNameReports returns whether a Prometheus metric name can be reported for a metric type: it is in the namespace
+// and contains the normalized metric type followed by nothing or a suffix. The monitored resource type is not known
+// from the descriptor, so a metric type whose normalized name extends another one may match too.
+func metricNameReports(name, metricType string) bool {
+	if !strings.HasPrefix(name, namespace+"_") {
+		return false
+	}
+	typeName := "_" + utils.NormalizeMetricName(metricType)
+	for offset := 0; ; {
+		i := strings.Index(name[offset:], typeName)
+		if i < 0 {
+			return false
+		}
+		end := offset + i + len(typeName)
+		if end == len(name) || name[end] == '_' {
+			return true
+		}
+		offset += i + 1
+	}
+}
+
+// ExtractMetricNames returns the distinct metric names of the exporter referenced in a text, such as Prometheus
+// rules or a dashboard definition, in order.
+func ExtractMetricNames(content []byte) []string {
+	names := metricNameRE.FindAllString(string(content), -1)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
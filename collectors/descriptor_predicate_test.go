@@ -0,0 +1,222 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func newTestDescriptorWithMetadata(metricType, samplePeriod, launchStage string) *monitoring.MetricDescriptor {
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	descriptor.LaunchStage = launchStage
+	if samplePeriod != "" {
+		descriptor.Metadata = &monitoring.MetricDescriptorMetadata{SamplePeriod: samplePeriod}
+	}
+	return descriptor
+}
+
+func TestDescriptorPredicateMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		predicate  DescriptorPredicate
+		descriptor *monitoring.MetricDescriptor
+		expected   bool
+	}{
+		{
+			name:       "empty predicate",
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   true,
+		},
+		{
+			name:       "sample period below the maximum",
+			predicate:  DescriptorPredicate{MaxSamplePeriod: time.Minute},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "10s", ""),
+			expected:   true,
+		},
+		{
+			name:       "sample period equal to the maximum",
+			predicate:  DescriptorPredicate{MaxSamplePeriod: time.Minute},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "60s", ""),
+			expected:   true,
+		},
+		{
+			name:       "sample period above the maximum",
+			predicate:  DescriptorPredicate{MaxSamplePeriod: time.Minute},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "300s", ""),
+			expected:   false,
+		},
+		{
+			name:       "missing sample period",
+			predicate:  DescriptorPredicate{MaxSamplePeriod: time.Minute},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   false,
+		},
+		{
+			name:       "matching launch stage",
+			predicate:  DescriptorPredicate{LaunchStages: []string{"GA", "BETA"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", "BETA"),
+			expected:   true,
+		},
+		{
+			name:       "launch stage from the metadata",
+			predicate:  DescriptorPredicate{LaunchStages: []string{"GA"}},
+			descriptor: &monitoring.MetricDescriptor{Metadata: &monitoring.MetricDescriptorMetadata{LaunchStage: "GA"}},
+			expected:   true,
+		},
+		{
+			name:       "other launch stage",
+			predicate:  DescriptorPredicate{LaunchStages: []string{"GA"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", "ALPHA"),
+			expected:   false,
+		},
+		{
+			name:       "reported metric name",
+			predicate:  DescriptorPredicate{MetricNames: []string{"stackdriver_gce_instance_custom_googleapis_com_a"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   true,
+		},
+		{
+			name:       "reported metric name with a suffix",
+			predicate:  DescriptorPredicate{MetricNames: []string{"stackdriver_gce_instance_custom_googleapis_com_a_bucket"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   true,
+		},
+		{
+			name:       "metric name of another metric type",
+			predicate:  DescriptorPredicate{MetricNames: []string{"stackdriver_gce_instance_custom_googleapis_com_ab"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   false,
+		},
+		{
+			name:       "metric name outside the namespace",
+			predicate:  DescriptorPredicate{MetricNames: []string{"custom_googleapis_com_a"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "", ""),
+			expected:   false,
+		},
+		{
+			name:       "all conditions must match",
+			predicate:  DescriptorPredicate{MaxSamplePeriod: time.Minute, LaunchStages: []string{"GA"}},
+			descriptor: newTestDescriptorWithMetadata("custom.googleapis.com/a", "60s", "BETA"),
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.predicate.Matches(tt.descriptor); got != tt.expected {
+				t.Errorf("Expected Matches() to be %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExtractMetricNames(t *testing.T) {
+	content := []byte(`groups:
+- name: example
+  rules:
+  - alert: HighLatency
+    expr: histogram_quantile(0.99, rate(stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket[5m])) > 1
+  - record: instance:cpu:rate5m
+    expr: sum(rate(stackdriver_gce_instance_compute_googleapis_com_instance_cpu_usage_time[5m])) / sum(up)
+  - alert: CPU
+    expr: instance:cpu:rate5m > 0.9 and on() stackdriver_gce_instance_compute_googleapis_com_instance_cpu_usage_time > 0
+`)
+
+	expected := []string{
+		"stackdriver_gce_instance_compute_googleapis_com_instance_cpu_usage_time",
+		"stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket",
+	}
+	if got := ExtractMetricNames(content); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDescriptorPredicateScrape(t *testing.T) {
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptorWithMetadata("custom.googleapis.com/fast_ga", "60s", "GA"),
+			newTestDescriptorWithMetadata("custom.googleapis.com/slow_ga", "300s", "GA"),
+			newTestDescriptorWithMetadata("custom.googleapis.com/fast_beta", "60s", "BETA"),
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		ExplicitTargets:    []MetricTarget{{MetricType: "other.googleapis.com/target"}},
+		DescriptorPredicate: DescriptorPredicate{
+			MaxSamplePeriod: time.Minute,
+			LaunchStages:    []string{"GA"},
+		},
+		RequestInterval: 5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	gatherFamilies(t, collector)
+
+	var scraped []string
+	for _, request := range api.timeSeriesRequests {
+		if m := fakeMetricTypeRE.FindStringSubmatch(request.Get("filter")); m != nil {
+			scraped = append(scraped, m[1])
+		}
+	}
+	sort.Strings(scraped)
+
+	// The explicit targets have no metadata and are not filtered
+	expected := []string{"custom.googleapis.com/fast_ga", "other.googleapis.com/target"}
+	if !slices.Equal(scraped, expected) {
+		t.Errorf("Expected %v to be scraped, got %v", expected, scraped)
+	}
+}
+
+func TestDescriptorPredicateMetricNamesScrape(t *testing.T) {
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/used", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/unused", "GAUGE", "DOUBLE"),
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		DescriptorPredicate: DescriptorPredicate{
+			MetricNames: ExtractMetricNames([]byte(`sum(stackdriver_gce_instance_custom_googleapis_com_used) by (zone)`)),
+		},
+		RequestInterval: 5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	gatherFamilies(t, collector)
+
+	var scraped []string
+	for _, request := range api.timeSeriesRequests {
+		if m := fakeMetricTypeRE.FindStringSubmatch(request.Get("filter")); m != nil {
+			scraped = append(scraped, m[1])
+		}
+	}
+
+	expected := []string{"custom.googleapis.com/used"}
+	if !slices.Equal(scraped, expected) {
+		t.Errorf("Expected %v to be scraped, got %v", expected, scraped)
+	}
+}
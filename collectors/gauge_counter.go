@@ -0,0 +1,88 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGaugeCounterTTL is how long the previous value of a GAUGE series converted to a counter is retained when no
+// TTL is set.
+const DefaultGaugeCounterTTL = 30 * time.Minute
+
+type gaugeSample struct {
+	value   float64
+	endTime time.Time
+	seen    time.Time
+}
+
+// gaugeCounterTracker retains the previous value of each GAUGE series which is converted to a counter, in order to
+// reconstruct the increments between consecutive scrapes.
+type gaugeCounterTracker struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	previous map[string]gaugeSample
+}
+
+func newGaugeCounterTracker(ttl time.Duration) *gaugeCounterTracker {
+	return &gaugeCounterTracker{
+		ttl:      ttl,
+		previous: make(map[string]gaugeSample),
+	}
+}
+
+// increment returns the increment of a series since its previous value. The first value of a series and a value lower
+// than the previous one are treated as the start of a new counter, their increment is the value itself. No increment
+// is returned when the point is not newer than the previous one.
+func (g *gaugeCounterTracker) increment(key string, value float64, endTime time.Time) (float64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous, ok := g.previous[key]
+	if ok && !endTime.After(previous.endTime) {
+		return 0, false
+	}
+	g.previous[key] = gaugeSample{value: value, endTime: endTime, seen: time.Now()}
+
+	if !ok || value < previous.value {
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// expire forgets the series which have not been seen within the TTL.
+func (g *gaugeCounterTracker) expire() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ttlWindowStart := time.Now().Add(-g.ttl)
+	for key, sample := range g.previous {
+		if ttlWindowStart.After(sample.seen) {
+			delete(g.previous, key)
+		}
+	}
+}
+
+// gaugeCounterKey identifies a series by its name and labels.
+func gaugeCounterKey(fqName string, labelKeys, labelValues []string) string {
+	parts := make([]string, 0, len(labelKeys))
+	for i := range labelKeys {
+		parts = append(parts, labelKeys[i]+"="+labelValues[i])
+	}
+	sort.Strings(parts)
+	return fqName + "|" + strings.Join(parts, "|")
+}
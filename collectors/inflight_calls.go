@@ -0,0 +1,66 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sync"
+	"time"
+)
+
+// inFlightCalls tracks the start time of the API calls in progress. A nil inFlightCalls tracks nothing.
+type inFlightCalls struct {
+	mu     sync.Mutex
+	nextID uint64
+	calls  map[uint64]time.Time
+}
+
+func newInFlightCalls() *inFlightCalls {
+	return &inFlightCalls{calls: make(map[uint64]time.Time)}
+}
+
+// start records the start of an API call and returns the function to call once it is done.
+func (f *inFlightCalls) start() (done func()) {
+	if f == nil {
+		return func() {}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextID
+	f.nextID++
+	f.calls[id] = time.Now()
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.calls, id)
+	}
+}
+
+// oldestAge returns the age in seconds of the oldest API call in progress, 0 if there is none.
+func (f *inFlightCalls) oldestAge() float64 {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var oldest time.Time
+	for _, started := range f.calls {
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest).Seconds()
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sync"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+// labelLayout is the resolved order of the unit, metric and resource label keys of the time series of a metric
+// descriptor, once the duplicate keys are dropped.
+type labelLayout struct {
+	// resourceType is part of the layout as the resource label keys may be prefixed by its display name
+	resourceType string
+	metricKeys   []string
+	resourceKeys []string
+	// keptMetricKeys and keptResourceKeys are the raw label keys which are not duplicates
+	keptMetricKeys   []string
+	keptResourceKeys []string
+	labelKeys        []string
+}
+
+// matches returns whether a time series has the monitored resource type and exactly the label keys of the layout.
+func (l *labelLayout) matches(timeSeries *monitoring.TimeSeries) bool {
+	if timeSeries.Resource.Type != l.resourceType {
+		return false
+	}
+	if len(timeSeries.Metric.Labels) != len(l.metricKeys) || len(timeSeries.Resource.Labels) != len(l.resourceKeys) {
+		return false
+	}
+	for _, key := range l.metricKeys {
+		if _, ok := timeSeries.Metric.Labels[key]; !ok {
+			return false
+		}
+	}
+	for _, key := range l.resourceKeys {
+		if _, ok := timeSeries.Resource.Labels[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// labels returns the label keys and values of a time series matching the layout. The keys are copied as they are
+// appended to by the caller.
+func (l *labelLayout) labels(unit string, timeSeries *monitoring.TimeSeries) ([]string, []string) {
+	labelKeys := append(make([]string, 0, len(l.labelKeys)), l.labelKeys...)
+	labelValues := make([]string, 0, len(l.labelKeys))
+	labelValues = append(labelValues, unit)
+	for _, key := range l.keptMetricKeys {
+		labelValues = append(labelValues, timeSeries.Metric.Labels[key])
+	}
+	for _, key := range l.keptResourceKeys {
+		labelValues = append(labelValues, timeSeries.Resource.Labels[key])
+	}
+	return labelKeys, labelValues
+}
+
+// labelLayoutCache holds the label layout of the metric descriptors by type. The layout of a metric type is replaced
+// when one of its time series has other label keys. A nil labelLayoutCache holds nothing.
+type labelLayoutCache struct {
+	mu      sync.Mutex
+	layouts map[string]*labelLayout
+}
+
+func newLabelLayoutCache() *labelLayoutCache {
+	return &labelLayoutCache{layouts: make(map[string]*labelLayout)}
+}
+
+func (l *labelLayoutCache) lookup(metricType string) *labelLayout {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.layouts[metricType]
+}
+
+func (l *labelLayoutCache) store(metricType string, layout *labelLayout) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.layouts[metricType] = layout
+}
+
+// reset drops all the layouts, i.e. when the resource label keys are remapped.
+func (l *labelLayoutCache) reset() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.layouts)
+}
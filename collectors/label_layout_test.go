@@ -0,0 +1,196 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func newLabelLayoutTestCollector(t testing.TB, cache bool) *MonitoringCollector {
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		LabelLayoutCache:   cache,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	return collector
+}
+
+func newLabelLayoutTestSeries(metricType string, metricLabels, resourceLabels map[string]string) *monitoring.TimeSeries {
+	timeSeries := newTestTimeSeries(metricType, "GAUGE", 1, time.Now())
+	timeSeries.Metric.Labels = metricLabels
+	timeSeries.Resource.Labels = resourceLabels
+	return timeSeries
+}
+
+func labelsMap(t *testing.T, labelKeys, labelValues []string) map[string]string {
+	t.Helper()
+	if len(labelKeys) != len(labelValues) {
+		t.Fatalf("Expected as many label keys as values, got %v and %v", labelKeys, labelValues)
+	}
+	labels := make(map[string]string, len(labelKeys))
+	for i, key := range labelKeys {
+		if _, ok := labels[key]; ok {
+			t.Fatalf("Duplicate label key %q in %v", key, labelKeys)
+		}
+		labels[key] = labelValues[i]
+	}
+	return labels
+}
+
+func TestLabelLayoutCache(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	descriptor.Unit = "1"
+
+	tests := []struct {
+		name           string
+		metricLabels   map[string]string
+		resourceLabels map[string]string
+		expected       map[string]string
+	}{
+		{
+			name:           "first series",
+			metricLabels:   map[string]string{"method": "GET", "unit": "ignored"},
+			resourceLabels: map[string]string{"project_id": "test-project", "method": "ignored", "zone": "a"},
+			expected:       map[string]string{"unit": "1", "method": "GET", "project_id": "test-project", "zone": "a"},
+		},
+		{
+			name:           "same label keys",
+			metricLabels:   map[string]string{"method": "POST", "unit": "ignored"},
+			resourceLabels: map[string]string{"project_id": "test-project", "method": "ignored", "zone": "b"},
+			expected:       map[string]string{"unit": "1", "method": "POST", "project_id": "test-project", "zone": "b"},
+		},
+		{
+			name:           "new label key",
+			metricLabels:   map[string]string{"method": "GET", "code": "200"},
+			resourceLabels: map[string]string{"project_id": "test-project", "zone": "a"},
+			expected:       map[string]string{"unit": "1", "method": "GET", "code": "200", "project_id": "test-project", "zone": "a"},
+		},
+		{
+			name:           "same count of other label keys",
+			metricLabels:   map[string]string{"method": "GET", "status": "ok"},
+			resourceLabels: map[string]string{"project_id": "test-project", "region": "europe"},
+			expected:       map[string]string{"unit": "1", "method": "GET", "status": "ok", "project_id": "test-project", "region": "europe"},
+		},
+	}
+
+	for _, cache := range []bool{false, true} {
+		t.Run(strconv.FormatBool(cache), func(t *testing.T) {
+			collector := newLabelLayoutTestCollector(t, cache)
+			for _, tt := range tests {
+				timeSeries := newLabelLayoutTestSeries(metricType, tt.metricLabels, tt.resourceLabels)
+				labelKeys, labelValues := collector.seriesLabels(descriptor, timeSeries)
+				if got := labelsMap(t, labelKeys, labelValues); !maps.Equal(got, tt.expected) {
+					t.Errorf("%s: expected labels %v, got %v", tt.name, tt.expected, got)
+				}
+				if labelKeys[0] != "unit" {
+					t.Errorf("%s: expected the unit label first, got %v", tt.name, labelKeys)
+				}
+
+				layout := collector.labelLayouts.lookup(metricType)
+				if cache != (layout != nil) {
+					t.Errorf("%s: expected a cached layout to be %v, got %v", tt.name, cache, layout)
+				}
+				if layout != nil && !layout.matches(timeSeries) {
+					t.Errorf("%s: expected the cached layout to match the last time series", tt.name)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelLayoutCacheResourceTypes(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	// The series of both resource types have the same raw label keys
+	var timeSeries []*monitoring.TimeSeries
+	for i, resourceType := range []string{"gce_instance", "gae_app", "gce_instance"} {
+		series := newTestTimeSeries(metricType, "GAUGE", 1, time.Now())
+		series.Resource.Type = resourceType
+		series.Resource.Labels["zone"] = strconv.Itoa(i)
+		timeSeries = append(timeSeries, series)
+	}
+
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+		resources: []*monitoring.MonitoredResourceDescriptor{
+			{Type: "gce_instance", DisplayName: "VM Instance"},
+			{Type: "gae_app", DisplayName: "App Engine"},
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: timeSeries}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:    []string{"custom.googleapis.com"},
+		RequestInterval:       5 * time.Minute,
+		ResourceDisplayLabels: true,
+		LabelLayoutCache:      true,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+	for fqName, expected := range map[string]string{
+		"stackdriver_gce_instance_custom_googleapis_com_requests": "vm_instance_zone",
+		"stackdriver_gae_app_custom_googleapis_com_requests":      "app_engine_zone",
+	} {
+		family, ok := families[fqName]
+		if !ok {
+			t.Fatalf("Expected %s to be reported", fqName)
+		}
+		for _, metric := range family.GetMetric() {
+			if labelValue(metric, expected) == "" {
+				t.Errorf("Expected %s to have the %s label, got %v", fqName, expected, metric.GetLabel())
+			}
+		}
+	}
+}
+
+func BenchmarkSeriesLabels(b *testing.B) {
+	metricType := "custom.googleapis.com/requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	metricLabels := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		metricLabels[fmt.Sprintf("metric_label_%d", i)] = strconv.Itoa(i)
+	}
+	resourceLabels := map[string]string{"project_id": "test-project"}
+	for i := 0; i < 5; i++ {
+		resourceLabels[fmt.Sprintf("resource_label_%d", i)] = strconv.Itoa(i)
+	}
+	timeSeries := newLabelLayoutTestSeries(metricType, metricLabels, resourceLabels)
+
+	for _, cache := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache=%v", cache), func(b *testing.B) {
+			collector := newLabelLayoutTestCollector(b, cache)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				collector.seriesLabels(descriptor, timeSeries)
+			}
+		})
+	}
+}
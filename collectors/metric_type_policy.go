@@ -0,0 +1,59 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricTypeKey identifies a Google Monitoring metric kind, i.e. GAUGE, DELTA or CUMULATIVE, depending on whether
+// DELTA metrics are aggregated.
+type MetricTypeKey struct {
+	MetricKind      string
+	AggregateDeltas bool
+}
+
+// MetricTypePolicy decides the Prometheus type of the metrics reported for each metric kind. Time series whose metric
+// kind is not in the policy are discarded.
+type MetricTypePolicy map[MetricTypeKey]prometheus.ValueType
+
+// DefaultMetricTypePolicy returns the policy used when none is configured: GAUGE metrics are reported as gauges,
+// CUMULATIVE metrics as counters and DELTA metrics as gauges, or as counters when they are aggregated.
+func DefaultMetricTypePolicy() MetricTypePolicy {
+	return MetricTypePolicy{
+		{MetricKind: "GAUGE", AggregateDeltas: false}:      prometheus.GaugeValue,
+		{MetricKind: "GAUGE", AggregateDeltas: true}:       prometheus.GaugeValue,
+		{MetricKind: "DELTA", AggregateDeltas: false}:      prometheus.GaugeValue,
+		{MetricKind: "DELTA", AggregateDeltas: true}:       prometheus.CounterValue,
+		{MetricKind: "CUMULATIVE", AggregateDeltas: false}: prometheus.CounterValue,
+		{MetricKind: "CUMULATIVE", AggregateDeltas: true}:  prometheus.CounterValue,
+	}
+}
+
+// validate returns an error if the policy reports the DELTA metrics which are not aggregated as counters: their raw
+// values go up and down from one interval to the next, so rate() would be meaningless.
+func (p MetricTypePolicy) validate() error {
+	if valueType, ok := p.valueType("DELTA", false); ok && valueType == prometheus.CounterValue {
+		return errors.New("DELTA metrics which are not aggregated cannot be reported as counters")
+	}
+	return nil
+}
+
+// valueType returns the Prometheus type of a metric kind, and false if the metric kind is not supported.
+func (p MetricTypePolicy) valueType(metricKind string, aggregateDeltas bool) (prometheus.ValueType, bool) {
+	valueType, ok := p[MetricTypeKey{MetricKind: metricKind, AggregateDeltas: aggregateDeltas}]
+	return valueType, ok
+}
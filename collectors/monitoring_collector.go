@@ -19,12 +19,17 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/monitoring/v3"
 
 	"github.com/prometheus-community/stackdriver_exporter/utils"
@@ -32,24 +37,78 @@ import (
 
 const namespace = "stackdriver"
 
+const (
+	// FuturePointsKeep reports points with an end time in the future as is.
+	FuturePointsKeep = "keep"
+	// FuturePointsDrop discards time series whose newest point has an end time in the future.
+	FuturePointsDrop = "drop"
+	// FuturePointsClamp reports points with an end time in the future with the current time.
+	FuturePointsClamp = "clamp"
+)
+
+const (
+	// NewestPointFirst reports the first of the points sharing the newest end time, in the order returned by the API.
+	NewestPointFirst = "first"
+	// NewestPointLast reports the last of the points sharing the newest end time, in the order returned by the API.
+	NewestPointLast = "last"
+	// NewestPointSum reports the sum of the points sharing the newest end time for DELTA INT64 and DOUBLE time series,
+	// and the first of them otherwise.
+	NewestPointSum = "sum"
+)
+
 type MetricFilter struct {
-	TargetedMetricPrefix string
-	FilterQuery          string
+	TargetedMetricPrefix string `yaml:"targeted_metric_prefix"`
+	FilterQuery          string `yaml:"filter_query"`
 }
 
 type MetricAggregationConfig struct {
-	TargetedMetricPrefix string
-	AlignmentPeriod      string
-	CrossSeriesReducer   string
-	GroupByFields        []string
-	PerSeriesAligner     string
+	TargetedMetricPrefix string   `yaml:"targeted_metric_prefix"`
+	AlignmentPeriod      string   `yaml:"alignment_period"`
+	CrossSeriesReducer   string   `yaml:"cross_series_reducer"`
+	GroupByFields        []string `yaml:"group_by_fields"`
+	PerSeriesAligner     string   `yaml:"per_series_aligner"`
+}
+
+// DefaultValueTypeAligners returns the per series aligners used by metric kind and value type when a matching
+// aggregation config has no per series aligner: GAUGE scalars are aligned with ALIGN_MEAN and DELTA metrics with
+// ALIGN_DELTA. The API accepts no aligner for CUMULATIVE metrics and GAUGE distributions which keeps their type, they
+// have no default.
+func DefaultValueTypeAligners() map[string]string {
+	return map[string]string{
+		"GAUGE:INT64":        "ALIGN_MEAN",
+		"GAUGE:DOUBLE":       "ALIGN_MEAN",
+		"DELTA:INT64":        "ALIGN_DELTA",
+		"DELTA:DOUBLE":       "ALIGN_DELTA",
+		"DELTA:DISTRIBUTION": "ALIGN_DELTA",
+	}
+}
+
+// DefaultDeltaCounterPrefixes are the prefixes of the log-based metrics, which are almost always DELTA counters.
+var DefaultDeltaCounterPrefixes = []string{"logging.googleapis.com/user"}
+
+// MetricTarget is a metric type that is scraped without listing the metric descriptors of a prefix.
+type MetricTarget struct {
+	// ProjectID restricts the target to a single project. It applies to every project when empty.
+	ProjectID string
+	// MetricType is the full metric type, i.e. pubsub.googleapis.com/subscription/num_undelivered_messages.
+	MetricType string
+	// MetricKind and ValueType are optional and only used to build the metric descriptor of the target.
+	MetricKind string
+	ValueType  string
+	// Aggregation is optional and takes precedence over the MetricAggregationConfigs matching the metric type.
+	Aggregation *MetricAggregationConfig
 }
 
 type MonitoringCollector struct {
 	projectID                       string
 	metricsTypePrefixes             []string
+	metricTargets                   []MetricTarget
+	descriptorPredicate             DescriptorPredicate
 	metricsFilters                  []MetricFilter
+	resourceLabelFilters            map[string]string
+	valueTypeAligners               map[string]string
 	metricsAggregationConfigs       []MetricAggregationConfig
+	scrapeConfigFile                *ScrapeConfigFile
 	metricsInterval                 time.Duration
 	metricsOffset                   time.Duration
 	metricsIngestDelay              bool
@@ -60,28 +119,80 @@ type MonitoringCollector struct {
 	lastScrapeErrorMetric           prometheus.Gauge
 	lastScrapeTimestampMetric       prometheus.Gauge
 	lastScrapeDurationSecondsMetric prometheus.Gauge
+	descriptorScrapeErrorMetric     *prometheus.GaugeVec
+	emptyExplicitBucketsTotalMetric *prometheus.CounterVec
+	apiCallsLastScrapeMetric        prometheus.Gauge
+	futurePointsTotalMetric         *prometheus.CounterVec
+	histogramErrorsTotalMetric      *prometheus.CounterVec
+	unitConflictsTotalMetric        *prometheus.CounterVec
+	metricTypesScrapedMetric        prometheus.Gauge
+	overlappingScrapesTotalMetric   prometheus.Counter
+	apiPagesTotalMetric             prometheus.Counter
 	collectorFillMissingLabels      bool
 	monitoringDropDelegatedProjects bool
+	strictExplicitBuckets           bool
+	lastSeenMetrics                 bool
+	futurePoints                    string
+	newestPointTies                 string
+	unitConflicts                   string
+	unitConflictsWarned             sync.Map
+	distributionFallback            bool
 	logger                          *slog.Logger
 	counterStore                    DeltaCounterStore
 	histogramStore                  DeltaHistogramStore
 	aggregateDeltas                 bool
+	deltaCounterPrefixes            []string
+	metricTypePolicy                MetricTypePolicy
+	createdTimestamps               bool
+	nameSuffixes                    bool
+	scrapeSummaryLog                bool
+	postScrapeHook                  func(ScrapeSummary)
+	separateInternalMetrics         bool
 	descriptorCache                 DescriptorCache
+	descriptorCacheRefresh          bool
+	descriptorCacheRefreshing       sync.Map
+	descriptorCacheRefreshErrors    *prometheus.CounterVec
+	delegatedSeriesDroppedMetric    *prometheus.CounterVec
+	invalidPointsTotalMetric        *prometheus.CounterVec
+	missingDescriptorsTotalMetric   *prometheus.CounterVec
+	nativeHistograms                bool
+	labelLayouts                    *labelLayoutCache
+	configHashMetric                prometheus.Gauge
+	inFlightCalls                   *inFlightCalls
+	oldestAPICallAgeMetric          prometheus.GaugeFunc
+	hashedScrapeConfig              atomic.Pointer[ScrapeConfig]
+	descriptorsByValueTypeMetric    *prometheus.GaugeVec
+	descriptorsByMetricKindMetric   *prometheus.GaugeVec
+	concurrency                     semaphore
+	prefixConcurrency               map[string]int
+	gaugeCounterPrefixes            []string
+	gaugeCounters                   *gaugeCounterTracker
+	scrapeGuard                     *scrapeGuard
+	alignmentTuner                  *alignmentTuner
+	resourceLabelNames              *resourceLabelNames
 }
 
 type MonitoringCollectorOptions struct {
 	// MetricTypePrefixes are the Google Monitoring (ex-Stackdriver) metric type prefixes that the collector
 	// will be querying.
 	MetricTypePrefixes []string
+	// ExplicitTargets is a list of metric types that the collector will be querying without listing their metric
+	// descriptors. Targets of other projects are ignored.
+	ExplicitTargets []MetricTarget
+	// DescriptorPredicate selects the metric descriptors listed for the MetricTypePrefixes from their metadata.
+	DescriptorPredicate DescriptorPredicate
 	// ExtraFilters is a list of criteria to apply to each corresponding metric prefix query. If one or more are
 	// applicable to a given metric type prefix, they will be 'AND' concatenated.
 	ExtraFilters []MetricFilter
+	// ResourceLabelFilters is a map of monitored resource labels which the time series must match. Each entry is
+	// 'AND' concatenated to the query as `resource.labels.<key> = "<value>"`.
+	ResourceLabelFilters map[string]string
 	// MetricsWithAggregations is a list of metrics with aggregation options in the format: metric_name:cross_series_reducer:group_by_fields:per_series_aligner. Example: custom.googleapis.com/my_metric:REDUCE_SUM:metric.labels.instance_id,resource.labels.zone:ALIGN_MEAN
 	MetricAggregationConfigs []MetricAggregationConfig
 	// RequestInterval is the time interval used in each request to get metrics. If there are many data points returned
-	// during this interval, only the latest will be reported.
+	// during this interval, only the latest will be reported. It must be positive.
 	RequestInterval time.Duration
-	// RequestOffset is used to offset the requested interval into the past.
+	// RequestOffset is used to offset the requested interval into the past. It cannot be negative.
 	RequestOffset time.Duration
 	// IngestDelay decides if the ingestion delay specified in the metrics metadata is used when calculating the
 	// request time interval.
@@ -92,10 +203,112 @@ type MonitoringCollectorOptions struct {
 	DropDelegatedProjects bool
 	// AggregateDeltas decides if DELTA metrics should be treated as a counter using the provided counterStore/distributionStore or a gauge
 	AggregateDeltas bool
+	// CreatedTimestamps decides if the counters accumulated by the counter store should be reported with the time the
+	// store started accumulating them as created timestamp.
+	CreatedTimestamps bool
+	// MetricTypePolicy decides the Prometheus type of the metrics reported for each metric kind. DefaultMetricTypePolicy
+	// is used when nil.
+	MetricTypePolicy MetricTypePolicy
+	// SeparateInternalMetrics decides if the internal stackdriver_monitoring_* metrics should only be reported by the
+	// InternalCollector instead of alongside the scraped metrics.
+	SeparateInternalMetrics bool
 	// DescriptorCacheTTL is the TTL on the items in the descriptorCache which caches the MetricDescriptors for a MetricTypePrefix
 	DescriptorCacheTTL time.Duration
 	// DescriptorCacheOnlyGoogle decides whether only google specific descriptors should be cached or all
 	DescriptorCacheOnlyGoogle bool
+	// DescriptorCacheBackgroundRefresh decides if expired descriptor cache entries should still be used while they are
+	// refreshed in the background, instead of listing the metric descriptors during the scrape.
+	DescriptorCacheBackgroundRefresh bool
+	// DescriptorScrapeErrors decides if a per metric descriptor error gauge should be reported for each scrape.
+	DescriptorScrapeErrors bool
+	// StrictExplicitBuckets decides if DISTRIBUTION metrics with explicit buckets but no bounds should be discarded
+	// instead of being reported as a single +Inf bucket histogram.
+	StrictExplicitBuckets bool
+	// LastSeenMetrics decides if a `<metric>_last_seen_seconds` gauge with the end time of the newest point should
+	// be reported for each time series.
+	LastSeenMetrics bool
+	// APICallsLastScrape decides if the number of API calls made by the last scrape should be reported. The calls of
+	// concurrent scrapes and of the background refreshes are not included.
+	APICallsLastScrape bool
+	// FuturePoints decides how a newest point with an end time in the future is handled, one of FuturePointsKeep
+	// (default), FuturePointsDrop or FuturePointsClamp.
+	FuturePoints string
+	// NewestPointTies decides which value is reported when several points share the newest end time, one of
+	// NewestPointFirst (default), NewestPointLast or NewestPointSum.
+	NewestPointTies string
+	// UnitConflicts decides the unit label of a metric type whose descriptors, obtained from different projects, report
+	// different units, one of UnitConflictsWarn (default), UnitConflictsPick or UnitConflictsDrop.
+	UnitConflicts string
+	// DistributionFallback decides if the count and sum of a DISTRIBUTION metric should be reported as
+	// `<metric>_count` and `<metric>_sum` when no histogram can be generated from its buckets.
+	DistributionFallback bool
+	// GaugeCounterPrefixes are the metric type prefixes of GAUGE metrics which are monotonic and should be reported
+	// as counters. The increments between consecutive values of each series are fed into the counter store.
+	GaugeCounterPrefixes []string
+	// GaugeCounterTTL is how long the previous value of a GAUGE series converted to a counter is retained. A series
+	// which reappears after the TTL is treated as a new counter start. It cannot be negative, 0 means
+	// DefaultGaugeCounterTTL.
+	GaugeCounterTTL time.Duration
+	// MaxConcurrency caps the number of metric descriptors whose time series are requested concurrently, 0 means
+	// unlimited.
+	MaxConcurrency int
+	// PrefixConcurrency caps the number of metric descriptors of a metric type prefix whose time series are requested
+	// concurrently within a scrape, so that a prefix with many descriptors cannot use the whole MaxConcurrency.
+	PrefixConcurrency map[string]int
+	// DescriptorProfileMetrics decides if the number of scraped metric descriptors by value type and by metric kind
+	// should be reported.
+	DescriptorProfileMetrics bool
+	// SkipInvalidPoints decides if a point whose end time cannot be parsed should be skipped and counted, instead of
+	// failing the whole page of time series.
+	SkipInvalidPoints bool
+	// ValueTypeAligners are the per series aligners used when the aggregation config matching a metric has no per
+	// series aligner, by metric kind and value type ("GAUGE:DOUBLE") or by value type for every metric kind ("DOUBLE").
+	// Defaults to DefaultValueTypeAligners.
+	ValueTypeAligners map[string]string
+	// ConfigHashMetric decides if a hash of the resolved scrape configuration should be reported, to detect
+	// configuration drift.
+	ConfigHashMetric bool
+	// DeltaCounterPrefixes are the metric type prefixes whose DELTA metrics are aggregated as counters even when
+	// AggregateDeltas is disabled. Defaults to DefaultDeltaCounterPrefixes when nil, an empty slice disables it.
+	DeltaCounterPrefixes []string
+	// OverlappingScrapes decides what happens when a scrape starts while another one is in progress, one of
+	// OverlappingScrapesConcurrent (default), OverlappingScrapesSerialize or OverlappingScrapesReject.
+	OverlappingScrapes string
+	// ScrapeConfigFile holds additional aggregation configs and extra filters which can be reloaded.
+	ScrapeConfigFile *ScrapeConfigFile
+	// NameSuffixes decides if the conventional Prometheus suffixes should be appended to the metric names: the base
+	// unit of the metric descriptor (`_bytes` or `_seconds`) and `_total` for counters.
+	NameSuffixes bool
+	// ScrapeSummaryLog decides if a summary of every scrape should be logged at info level: the prefixes, descriptors,
+	// time series, points, API calls, duration and errors.
+	ScrapeSummaryLog bool
+	// PostScrapeHook is called at the end of every scrape, once its metrics have been reported, with a summary of the
+	// reported metric families, to validate them or derive signals from them. It delays the end of the scrape and
+	// should return quickly.
+	PostScrapeHook func(ScrapeSummary)
+	// MaxPointsPerSeries is the number of points per time series above which the alignment period of a metric type
+	// without aggregation config is increased, using the per series aligner of its value type. 0 disables it.
+	MaxPointsPerSeries int
+	// ResourceDisplayLabels decides if the monitored resource label keys should be prefixed with the display name of
+	// their resource descriptor, i.e. `vm_instance_zone` instead of `zone`. The raw keys are used when the resource
+	// descriptors cannot be listed.
+	ResourceDisplayLabels bool
+	// StartupValidatePrefixes decides if the collector creation should fail when a metric type prefix matches no
+	// metric descriptor, see Validate.
+	StartupValidatePrefixes bool
+	// OldestAPICallMetric decides if the age of the oldest API call in progress should be reported, to detect a hung
+	// call dragging out a scrape.
+	OldestAPICallMetric bool
+	// SkipMissingDescriptors decides if a metric type whose time series cannot be listed because it was deleted since
+	// its descriptor was listed should be skipped and counted, instead of failing the scrape.
+	SkipMissingDescriptors bool
+	// NativeHistograms decides if the exponential distributions should be reported as native histograms, with the
+	// schema closest to their growth factor. The DELTA distributions aggregated by the histogram store and the ones
+	// whose missing labels are filled are still reported as classic histograms, so FillMissingLabels must be disabled.
+	NativeHistograms bool
+	// LabelLayoutCache decides if the resolved order of the label keys of the time series should be cached by metric
+	// type, so that the time series with the same label keys as the previous ones skip the duplicate keys detection.
+	LabelLayoutCache bool
 }
 
 func isGoogleMetric(name string) bool {
@@ -114,6 +327,13 @@ func (d *googleDescriptorCache) Lookup(prefix string) []*monitoring.MetricDescri
 	return d.inner.Lookup(prefix)
 }
 
+func (d *googleDescriptorCache) LookupStale(prefix string) ([]*monitoring.MetricDescriptor, bool) {
+	if !isGoogleMetric(prefix) {
+		return nil, false
+	}
+	return d.inner.LookupStale(prefix)
+}
+
 func (d *googleDescriptorCache) Store(prefix string, data []*monitoring.MetricDescriptor) {
 	if !isGoogleMetric(prefix) {
 		return
@@ -121,6 +341,19 @@ func (d *googleDescriptorCache) Store(prefix string, data []*monitoring.MetricDe
 	d.inner.Store(prefix, data)
 }
 
+// staleDescriptorCache is a DescriptorCache which can return expired entries to be refreshed in the background.
+type staleDescriptorCache interface {
+	LookupStale(prefix string) ([]*monitoring.MetricDescriptor, bool)
+}
+
+// prefixKind returns the kind of metric type prefix used to label the descriptor cache metrics.
+func prefixKind(prefix string) string {
+	if isGoogleMetric(prefix) {
+		return "google"
+	}
+	return "custom"
+}
+
 type DeltaCounterStore interface {
 	Increment(metricDescriptor *monitoring.MetricDescriptor, currentValue *ConstMetric)
 	ListMetrics(metricDescriptorName string) []*ConstMetric
@@ -196,6 +429,290 @@ func NewMonitoringCollector(projectID string, monitoringService *monitoring.Serv
 		},
 	)
 
+	// A zero interval requests an empty time range and silently reports nothing
+	if opts.RequestInterval <= 0 {
+		return nil, fmt.Errorf("invalid request interval %s, it must be positive", opts.RequestInterval)
+	}
+	if opts.RequestOffset < 0 {
+		return nil, fmt.Errorf("invalid request offset %s, it cannot be negative", opts.RequestOffset)
+	}
+	switch {
+	case opts.GaugeCounterTTL < 0:
+		return nil, fmt.Errorf("invalid gauge counter TTL %s, it cannot be negative", opts.GaugeCounterTTL)
+	case opts.GaugeCounterTTL == 0:
+		// Without a TTL every previous value would expire on each scrape and be counted again
+		opts.GaugeCounterTTL = DefaultGaugeCounterTTL
+	}
+
+	switch opts.FuturePoints {
+	case "":
+		opts.FuturePoints = FuturePointsKeep
+	case FuturePointsKeep, FuturePointsDrop, FuturePointsClamp:
+	default:
+		return nil, fmt.Errorf("unknown future points policy %q", opts.FuturePoints)
+	}
+
+	metricTypePolicy := opts.MetricTypePolicy
+	if metricTypePolicy == nil {
+		metricTypePolicy = DefaultMetricTypePolicy()
+	}
+	if err := metricTypePolicy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid metric type policy: %w", err)
+	}
+
+	switch opts.OverlappingScrapes {
+	case "":
+		opts.OverlappingScrapes = OverlappingScrapesConcurrent
+	case OverlappingScrapesConcurrent, OverlappingScrapesSerialize, OverlappingScrapesReject:
+	default:
+		return nil, fmt.Errorf("unknown overlapping scrapes policy %q", opts.OverlappingScrapes)
+	}
+
+	switch opts.NewestPointTies {
+	case "":
+		opts.NewestPointTies = NewestPointFirst
+	case NewestPointFirst, NewestPointLast, NewestPointSum:
+	default:
+		return nil, fmt.Errorf("unknown newest point ties policy %q", opts.NewestPointTies)
+	}
+
+	switch opts.UnitConflicts {
+	case "":
+		opts.UnitConflicts = UnitConflictsWarn
+	case UnitConflictsWarn, UnitConflictsPick, UnitConflictsDrop:
+	default:
+		return nil, fmt.Errorf("unknown unit conflicts policy %q", opts.UnitConflicts)
+	}
+
+	if opts.MaxPointsPerSeries < 0 {
+		return nil, fmt.Errorf("invalid max points per series %d", opts.MaxPointsPerSeries)
+	}
+
+	if opts.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("invalid max concurrency %d", opts.MaxConcurrency)
+	}
+	for prefix, limit := range opts.PrefixConcurrency {
+		if limit < 0 {
+			return nil, fmt.Errorf("invalid concurrency %d for prefix %s", limit, prefix)
+		}
+	}
+
+	deltaCounterPrefixes := opts.DeltaCounterPrefixes
+	if deltaCounterPrefixes == nil {
+		deltaCounterPrefixes = DefaultDeltaCounterPrefixes
+	}
+
+	valueTypeAligners := opts.ValueTypeAligners
+	if valueTypeAligners == nil {
+		valueTypeAligners = DefaultValueTypeAligners()
+	}
+
+	// Invalid alignment periods would only fail when requesting the time series
+	aggregationConfigs := make([]MetricAggregationConfig, 0, len(opts.MetricAggregationConfigs))
+	for _, config := range opts.MetricAggregationConfigs {
+		alignmentPeriod, err := utils.NormalizeAlignmentPeriod(config.AlignmentPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregation for %s: %w", config.TargetedMetricPrefix, err)
+		}
+		config.AlignmentPeriod = alignmentPeriod
+		aggregationConfigs = append(aggregationConfigs, config)
+	}
+
+	metricTargets := projectMetricTargets(projectID, opts.ExplicitTargets)
+	for i, target := range metricTargets {
+		if target.Aggregation == nil {
+			continue
+		}
+		aggregation := *target.Aggregation
+		alignmentPeriod, err := utils.NormalizeAlignmentPeriod(aggregation.AlignmentPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregation for %s: %w", target.MetricType, err)
+		}
+		aggregation.AlignmentPeriod = alignmentPeriod
+		metricTargets[i].Aggregation = &aggregation
+	}
+
+	emptyExplicitBucketsTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "empty_explicit_buckets_total",
+			Help:        "Total number of Google Stackdriver Monitoring distributions received with explicit buckets but no bounds.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	futurePointsTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "future_points_total",
+			Help:        "Total number of Google Stackdriver Monitoring time series whose newest point has an end time in the future.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	histogramErrorsTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "histogram_errors_total",
+			Help:        "Total number of Google Stackdriver Monitoring distributions which could not be converted to a histogram.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	unitConflictsTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "unit_conflicts_total",
+			Help:        "Total number of Google Stackdriver Monitoring metrics scrapes in which the descriptors of a metric type reported different units.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	metricTypesScrapedMetric := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "metric_types_scraped",
+			Help:        "Number of distinct Google Stackdriver Monitoring metric types which reported at least one time series in the last scrape.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+	)
+
+	overlappingScrapesTotalMetric := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "overlapping_scrapes_total",
+			Help:        "Total number of Google Stackdriver Monitoring metrics scrapes started while another one was in progress.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+	)
+
+	apiPagesTotalMetric := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "api_pages_total",
+			Help:        "Total number of Google Stackdriver Monitoring time series pages fetched.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+	)
+
+	var descriptorCacheRefreshErrors *prometheus.CounterVec
+	if opts.DescriptorCacheBackgroundRefresh {
+		descriptorCacheRefreshErrors = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "descriptor_cache_refresh_errors_total",
+				Help:        "Total number of Google Stackdriver Monitoring metric descriptors background cache refresh errors.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"prefix_kind"},
+		)
+	}
+
+	var delegatedSeriesDroppedMetric *prometheus.CounterVec
+	if opts.DropDelegatedProjects {
+		delegatedSeriesDroppedMetric = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "delegated_series_dropped_total",
+				Help:        "Total number of Google Stackdriver Monitoring time series dropped because they belong to a delegated project.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"metric_type"},
+		)
+	}
+
+	var invalidPointsTotalMetric *prometheus.CounterVec
+	if opts.SkipInvalidPoints {
+		invalidPointsTotalMetric = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "invalid_points_total",
+				Help:        "Total number of Google Stackdriver Monitoring points skipped because their end time could not be parsed.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"metric_type"},
+		)
+	}
+
+	var missingDescriptorsTotalMetric *prometheus.CounterVec
+	if opts.SkipMissingDescriptors {
+		missingDescriptorsTotalMetric = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "missing_descriptors_total",
+				Help:        "Total number of Google Stackdriver Monitoring metric descriptors skipped because they were not found when listing their time series.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"metric_type"},
+		)
+	}
+
+	var descriptorsByValueTypeMetric, descriptorsByMetricKindMetric *prometheus.GaugeVec
+	if opts.DescriptorProfileMetrics {
+		descriptorsByValueTypeMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "descriptors_by_value_type",
+				Help:        "Number of Google Stackdriver Monitoring metric descriptors scraped in the last scrape by value type.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"value_type"},
+		)
+		descriptorsByMetricKindMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "descriptors_by_metric_kind",
+				Help:        "Number of Google Stackdriver Monitoring metric descriptors scraped in the last scrape by metric kind.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"metric_kind"},
+		)
+	}
+
+	var descriptorScrapeErrorMetric *prometheus.GaugeVec
+	if opts.DescriptorScrapeErrors {
+		descriptorScrapeErrorMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "descriptor_scrape_error",
+				Help:        "Whether the last scrape of a metric descriptor from Google Stackdriver Monitoring resulted in an error (1 for error, 0 for success).",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			[]string{"metric_type"},
+		)
+	}
+
+	var apiCallsLastScrapeMetric prometheus.Gauge
+	if opts.APICallsLastScrape {
+		apiCallsLastScrapeMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "api_calls_last_scrape",
+				Help:        "Number of Google Stackdriver Monitoring API calls made during the last metrics scrape.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+		)
+	}
+
 	var descriptorCache DescriptorCache
 	if opts.DescriptorCacheTTL == 0 {
 		descriptorCache = &noopDescriptorCache{}
@@ -208,68 +725,476 @@ func NewMonitoringCollector(projectID string, monitoringService *monitoring.Serv
 
 	monitoringCollector := &MonitoringCollector{
 		projectID:                       projectID,
+		metricTargets:                   metricTargets,
+		descriptorPredicate:             opts.DescriptorPredicate,
 		metricsTypePrefixes:             opts.MetricTypePrefixes,
 		metricsFilters:                  opts.ExtraFilters,
-		metricsAggregationConfigs:       opts.MetricAggregationConfigs,
+		resourceLabelFilters:            opts.ResourceLabelFilters,
+		metricsAggregationConfigs:       aggregationConfigs,
+		scrapeConfigFile:                opts.ScrapeConfigFile,
+		valueTypeAligners:               valueTypeAligners,
 		metricsInterval:                 opts.RequestInterval,
 		metricsOffset:                   opts.RequestOffset,
 		metricsIngestDelay:              opts.IngestDelay,
 		monitoringService:               monitoringService,
 		apiCallsTotalMetric:             apiCallsTotalMetric,
 		scrapesTotalMetric:              scrapesTotalMetric,
+		overlappingScrapesTotalMetric:   overlappingScrapesTotalMetric,
+		apiPagesTotalMetric:             apiPagesTotalMetric,
+		scrapeGuard:                     &scrapeGuard{policy: opts.OverlappingScrapes},
 		scrapeErrorsTotalMetric:         scrapeErrorsTotalMetric,
 		lastScrapeErrorMetric:           lastScrapeErrorMetric,
 		lastScrapeTimestampMetric:       lastScrapeTimestampMetric,
 		lastScrapeDurationSecondsMetric: lastScrapeDurationSecondsMetric,
+		descriptorScrapeErrorMetric:     descriptorScrapeErrorMetric,
+		emptyExplicitBucketsTotalMetric: emptyExplicitBucketsTotalMetric,
+		apiCallsLastScrapeMetric:        apiCallsLastScrapeMetric,
+		futurePointsTotalMetric:         futurePointsTotalMetric,
+		histogramErrorsTotalMetric:      histogramErrorsTotalMetric,
+		unitConflictsTotalMetric:        unitConflictsTotalMetric,
+		metricTypesScrapedMetric:        metricTypesScrapedMetric,
 		collectorFillMissingLabels:      opts.FillMissingLabels,
 		monitoringDropDelegatedProjects: opts.DropDelegatedProjects,
+		strictExplicitBuckets:           opts.StrictExplicitBuckets,
+		lastSeenMetrics:                 opts.LastSeenMetrics,
+		futurePoints:                    opts.FuturePoints,
+		newestPointTies:                 opts.NewestPointTies,
+		unitConflicts:                   opts.UnitConflicts,
+		distributionFallback:            opts.DistributionFallback,
 		logger:                          logger,
 		counterStore:                    counterStore,
 		histogramStore:                  histogramStore,
 		aggregateDeltas:                 opts.AggregateDeltas,
+		deltaCounterPrefixes:            deltaCounterPrefixes,
+		metricTypePolicy:                metricTypePolicy,
+		createdTimestamps:               opts.CreatedTimestamps,
+		nameSuffixes:                    opts.NameSuffixes,
+		scrapeSummaryLog:                opts.ScrapeSummaryLog,
+		postScrapeHook:                  opts.PostScrapeHook,
+		separateInternalMetrics:         opts.SeparateInternalMetrics,
 		descriptorCache:                 descriptorCache,
+		descriptorCacheRefresh:          opts.DescriptorCacheBackgroundRefresh,
+		descriptorCacheRefreshErrors:    descriptorCacheRefreshErrors,
+		delegatedSeriesDroppedMetric:    delegatedSeriesDroppedMetric,
+		invalidPointsTotalMetric:        invalidPointsTotalMetric,
+		missingDescriptorsTotalMetric:   missingDescriptorsTotalMetric,
+		nativeHistograms:                opts.NativeHistograms,
+		descriptorsByValueTypeMetric:    descriptorsByValueTypeMetric,
+		descriptorsByMetricKindMetric:   descriptorsByMetricKindMetric,
+		concurrency:                     newSemaphore(opts.MaxConcurrency),
+		prefixConcurrency:               opts.PrefixConcurrency,
+		gaugeCounterPrefixes:            opts.GaugeCounterPrefixes,
+		alignmentTuner:                  newAlignmentTuner(opts.MaxPointsPerSeries, opts.RequestInterval),
+	}
+
+	if len(opts.GaugeCounterPrefixes) > 0 {
+		monitoringCollector.gaugeCounters = newGaugeCounterTracker(opts.GaugeCounterTTL)
+	}
+
+	if opts.OldestAPICallMetric {
+		monitoringCollector.inFlightCalls = newInFlightCalls()
+		monitoringCollector.oldestAPICallAgeMetric = prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "oldest_api_call_age_seconds",
+				Help:        "Age of the oldest Google Stackdriver Monitoring API call in progress, 0 if there is none.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+			monitoringCollector.inFlightCalls.oldestAge,
+		)
+	}
+
+	if opts.ResourceDisplayLabels {
+		monitoringCollector.resourceLabelNames = newResourceLabelNames(monitoringService, projectID, apiCallsTotalMetric, monitoringCollector.inFlightCalls, logger)
+	}
+
+	if opts.LabelLayoutCache {
+		monitoringCollector.labelLayouts = newLabelLayoutCache()
+	}
+
+	if opts.StartupValidatePrefixes {
+		if err := monitoringCollector.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ConfigHashMetric {
+		monitoringCollector.configHashMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "config_hash",
+				Help:        "Hash of the resolved Google Stackdriver Monitoring scrape configuration.",
+				ConstLabels: prometheus.Labels{"project_id": projectID},
+			},
+		)
+		monitoringCollector.updateConfigHash(monitoringCollector.scrapeConfigFile.Config())
 	}
 
 	return monitoringCollector, nil
 }
 
 func (c *MonitoringCollector) Describe(ch chan<- *prometheus.Desc) {
+	if !c.separateInternalMetrics {
+		c.describeInternalMetrics(ch)
+	}
+}
+
+// describeInternalMetrics describes the internal stackdriver_monitoring_* metrics.
+func (c *MonitoringCollector) describeInternalMetrics(ch chan<- *prometheus.Desc) {
 	c.apiCallsTotalMetric.Describe(ch)
 	c.scrapesTotalMetric.Describe(ch)
 	c.scrapeErrorsTotalMetric.Describe(ch)
 	c.lastScrapeErrorMetric.Describe(ch)
 	c.lastScrapeTimestampMetric.Describe(ch)
 	c.lastScrapeDurationSecondsMetric.Describe(ch)
+	c.emptyExplicitBucketsTotalMetric.Describe(ch)
+	c.futurePointsTotalMetric.Describe(ch)
+	c.histogramErrorsTotalMetric.Describe(ch)
+	c.unitConflictsTotalMetric.Describe(ch)
+	c.metricTypesScrapedMetric.Describe(ch)
+	c.overlappingScrapesTotalMetric.Describe(ch)
+	c.apiPagesTotalMetric.Describe(ch)
+	if c.descriptorScrapeErrorMetric != nil {
+		c.descriptorScrapeErrorMetric.Describe(ch)
+	}
+	if c.apiCallsLastScrapeMetric != nil {
+		c.apiCallsLastScrapeMetric.Describe(ch)
+	}
+	if c.descriptorCacheRefreshErrors != nil {
+		c.descriptorCacheRefreshErrors.Describe(ch)
+	}
+	if c.delegatedSeriesDroppedMetric != nil {
+		c.delegatedSeriesDroppedMetric.Describe(ch)
+	}
+	if c.invalidPointsTotalMetric != nil {
+		c.invalidPointsTotalMetric.Describe(ch)
+	}
+
+	if c.missingDescriptorsTotalMetric != nil {
+		c.missingDescriptorsTotalMetric.Describe(ch)
+	}
+	if c.oldestAPICallAgeMetric != nil {
+		c.oldestAPICallAgeMetric.Describe(ch)
+	}
+
+	if c.configHashMetric != nil {
+		c.configHashMetric.Describe(ch)
+	}
+	if c.descriptorsByValueTypeMetric != nil {
+		c.descriptorsByValueTypeMetric.Describe(ch)
+		c.descriptorsByMetricKindMetric.Describe(ch)
+	}
 }
 
 func (c *MonitoringCollector) Collect(ch chan<- prometheus.Metric) {
+	overlapping := c.scrapeGuard.enter()
+	defer c.scrapeGuard.leave()
+	if overlapping {
+		c.overlappingScrapesTotalMetric.Inc()
+		if c.scrapeGuard.policy == OverlappingScrapesReject {
+			c.logger.Debug("scrape in progress, reporting the metrics of the last scrape")
+			c.scrapeGuard.replay(ch)
+			if !c.separateInternalMetrics {
+				c.collectInternalMetrics(ch)
+			}
+			return
+		}
+	}
+
+	if c.scrapeGuard.policy == OverlappingScrapesSerialize {
+		c.scrapeGuard.serial.Lock()
+		defer c.scrapeGuard.serial.Unlock()
+	}
+
 	var begun = time.Now()
 
+	// Only keep the descriptors scraped in this run to bound the cardinality
+	if c.descriptorScrapeErrorMetric != nil {
+		c.descriptorScrapeErrorMetric.Reset()
+	}
+
+	if c.gaugeCounters != nil {
+		c.gaugeCounters.expire()
+	}
+
 	errorMetric := float64(0)
-	if err := c.reportMonitoringMetrics(ch, begun); err != nil {
+	state := newScrapeState(c.scrapeConfigFile.Config())
+	if c.resourceLabelNames.load() {
+		// The layouts hold the resource label keys resolved before they were remapped
+		c.labelLayouts.reset()
+	}
+	scrapeCh, recorded := ch, func() {}
+	if c.scrapeGuard.policy == OverlappingScrapesReject {
+		scrapeCh, recorded = c.scrapeGuard.record(ch)
+	}
+	err := c.reportMonitoringMetrics(scrapeCh, begun, state)
+	if err != nil {
 		errorMetric = float64(1)
 		c.scrapeErrorsTotalMetric.Inc()
 		c.logger.Error("Error while getting Google Stackdriver Monitoring metrics", "err", err)
 	}
-	c.scrapeErrorsTotalMetric.Collect(ch)
-
-	c.apiCallsTotalMetric.Collect(ch)
+	recorded()
 
 	c.scrapesTotalMetric.Inc()
-	c.scrapesTotalMetric.Collect(ch)
-
 	c.lastScrapeErrorMetric.Set(errorMetric)
-	c.lastScrapeErrorMetric.Collect(ch)
-
 	c.lastScrapeTimestampMetric.Set(float64(time.Now().Unix()))
-	c.lastScrapeTimestampMetric.Collect(ch)
-
 	c.lastScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.metricTypesScrapedMetric.Set(float64(state.metricTypesCount()))
+
+	if c.descriptorsByValueTypeMetric != nil {
+		byValueType, byMetricKind := state.descriptorCounts()
+		c.descriptorsByValueTypeMetric.Reset()
+		for valueType, count := range byValueType {
+			c.descriptorsByValueTypeMetric.WithLabelValues(valueType).Set(float64(count))
+		}
+		c.descriptorsByMetricKindMetric.Reset()
+		for metricKind, count := range byMetricKind {
+			c.descriptorsByMetricKindMetric.WithLabelValues(metricKind).Set(float64(count))
+		}
+	}
+
+	if c.configHashMetric != nil {
+		c.updateConfigHash(state.scrapeConfig())
+	}
+
+	if c.apiCallsLastScrapeMetric != nil {
+		c.apiCallsLastScrapeMetric.Set(float64(state.apiCallsCount()))
+	}
+
+	if c.scrapeSummaryLog {
+		descriptors, series, samples, errors := state.counts()
+		c.logger.Info("Scrape summary",
+			"prefixes", len(c.metricsTypePrefixes),
+			"descriptors", descriptors,
+			"series", series,
+			"samples", samples,
+			"api_calls", state.apiCallsCount(),
+			"duration", time.Since(begun),
+			"errors", errors,
+		)
+	}
+
+	if !c.separateInternalMetrics {
+		c.collectInternalMetrics(ch)
+	}
+
+	if c.postScrapeHook != nil {
+		summary := ScrapeSummary{ProjectID: c.projectID, Families: state.familyCounts()}
+		for _, count := range summary.Families {
+			summary.Metrics += count
+		}
+		summary.Descriptors, summary.Series, summary.Samples, summary.Errors = state.counts()
+		summary.Duration = time.Since(begun)
+		summary.Err = err
+		c.postScrapeHook(summary)
+	}
+}
+
+// Validate lists the metric descriptors of every metric type prefix and returns an error if a prefix matches none,
+// which is most likely a typo. The listed descriptors are stored in the descriptor cache, if any.
+func (c *MonitoringCollector) Validate() error {
+	var empty []string
+	for _, metricsTypePrefix := range c.metricsTypePrefixes {
+		descriptors, err := c.listMetricDescriptors(metricsTypePrefix, nil, nil)
+		if err != nil {
+			return fmt.Errorf("error listing the metric descriptors of prefix %s: %w", metricsTypePrefix, err)
+		}
+		if len(descriptors) == 0 {
+			empty = append(empty, metricsTypePrefix)
+			continue
+		}
+		c.descriptorCache.Store(metricsTypePrefix, descriptors)
+	}
+	if len(empty) > 0 {
+		return fmt.Errorf("metric type prefixes matching no metric descriptor in project %s: %s", c.projectID, strings.Join(empty, ", "))
+	}
+	return nil
+}
+
+// updateConfigHash sets the config hash metric when the scrape configuration loaded from the file changed.
+func (c *MonitoringCollector) updateConfigHash(config *ScrapeConfig) {
+	if c.hashedScrapeConfig.Swap(config) != config {
+		c.configHashMetric.Set(float64(c.configHash(config)))
+	}
+}
+
+// collectInternalMetrics collects the internal stackdriver_monitoring_* metrics as of the last scrape.
+func (c *MonitoringCollector) collectInternalMetrics(ch chan<- prometheus.Metric) {
+	c.scrapeErrorsTotalMetric.Collect(ch)
+	c.apiCallsTotalMetric.Collect(ch)
+	c.scrapesTotalMetric.Collect(ch)
+	c.lastScrapeErrorMetric.Collect(ch)
+	c.lastScrapeTimestampMetric.Collect(ch)
 	c.lastScrapeDurationSecondsMetric.Collect(ch)
+	c.emptyExplicitBucketsTotalMetric.Collect(ch)
+	c.futurePointsTotalMetric.Collect(ch)
+	c.histogramErrorsTotalMetric.Collect(ch)
+	c.unitConflictsTotalMetric.Collect(ch)
+	c.metricTypesScrapedMetric.Collect(ch)
+	c.overlappingScrapesTotalMetric.Collect(ch)
+	c.apiPagesTotalMetric.Collect(ch)
+
+	if c.descriptorScrapeErrorMetric != nil {
+		c.descriptorScrapeErrorMetric.Collect(ch)
+	}
+
+	if c.apiCallsLastScrapeMetric != nil {
+		c.apiCallsLastScrapeMetric.Collect(ch)
+	}
+
+	if c.descriptorCacheRefreshErrors != nil {
+		c.descriptorCacheRefreshErrors.Collect(ch)
+	}
+
+	if c.delegatedSeriesDroppedMetric != nil {
+		c.delegatedSeriesDroppedMetric.Collect(ch)
+	}
+
+	if c.invalidPointsTotalMetric != nil {
+		c.invalidPointsTotalMetric.Collect(ch)
+	}
+
+	if c.missingDescriptorsTotalMetric != nil {
+		c.missingDescriptorsTotalMetric.Collect(ch)
+	}
+
+	if c.oldestAPICallAgeMetric != nil {
+		c.oldestAPICallAgeMetric.Collect(ch)
+	}
+
+	if c.configHashMetric != nil {
+		c.configHashMetric.Collect(ch)
+	}
+
+	if c.descriptorsByValueTypeMetric != nil {
+		c.descriptorsByValueTypeMetric.Collect(ch)
+		c.descriptorsByMetricKindMetric.Collect(ch)
+	}
+}
+
+// internalMetricsCollector reports the internal metrics of a MonitoringCollector on their own.
+type internalMetricsCollector struct {
+	collector *MonitoringCollector
+}
+
+func (i *internalMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	i.collector.describeInternalMetrics(ch)
+}
+
+func (i *internalMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	i.collector.collectInternalMetrics(ch)
+}
+
+// InternalCollector returns a collector of the internal stackdriver_monitoring_* metrics as of the last scrape. It is
+// meant to be registered in a separate registry along with the SeparateInternalMetrics option.
+func (c *MonitoringCollector) InternalCollector() prometheus.Collector {
+	return &internalMetricsCollector{collector: c}
+}
+
+// projectMetricTargets returns the explicit targets which apply to a project, keeping the first target of each
+// metric type.
+func projectMetricTargets(projectID string, targets []MetricTarget) []MetricTarget {
+	var projectTargets []MetricTarget
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if target.ProjectID != "" && target.ProjectID != projectID {
+			continue
+		}
+		if seen[target.MetricType] {
+			continue
+		}
+		seen[target.MetricType] = true
+		projectTargets = append(projectTargets, target)
+	}
+	return projectTargets
+}
+
+// targetDescriptors builds the metric descriptors of the explicit targets, split between the ones whose metric type
+// starts with one of the metric type prefixes and the others.
+func (c *MonitoringCollector) targetDescriptors() (covered, uncovered []*monitoring.MetricDescriptor) {
+	for _, target := range c.metricTargets {
+
+		metricKind := target.MetricKind
+		if metricKind == "" {
+			metricKind = "GAUGE"
+		}
+		valueType := target.ValueType
+		if valueType == "" {
+			valueType = "DOUBLE"
+		}
+		descriptor := &monitoring.MetricDescriptor{
+			Name:       fmt.Sprintf("%s/metricDescriptors/%s", utils.ProjectResource(c.projectID), target.MetricType),
+			Type:       target.MetricType,
+			MetricKind: metricKind,
+			ValueType:  valueType,
+		}
+		if slices.ContainsFunc(c.metricsTypePrefixes, func(prefix string) bool { return strings.HasPrefix(target.MetricType, prefix) }) {
+			covered = append(covered, descriptor)
+		} else {
+			uncovered = append(uncovered, descriptor)
+		}
+	}
+	return covered, uncovered
+}
+
+// valueTypeAligner returns the per series aligner of the metric kind and value type of a metric descriptor, or of its
+// value type for every metric kind, if any.
+func (c *MonitoringCollector) valueTypeAligner(metricDescriptor *monitoring.MetricDescriptor) string {
+	if aligner, ok := c.valueTypeAligners[metricDescriptor.MetricKind+":"+metricDescriptor.ValueType]; ok {
+		return aligner
+	}
+	return c.valueTypeAligners[metricDescriptor.ValueType]
+}
+
+// isGaugeCounter returns whether the GAUGE metrics of a metric type should be reported as counters.
+func (c *MonitoringCollector) isGaugeCounter(metricType string) bool {
+	for _, prefix := range c.gaugeCounterPrefixes {
+		if strings.HasPrefix(metricType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregatesDeltas returns whether the DELTA metrics of a metric type are aggregated as counters.
+func (c *MonitoringCollector) aggregatesDeltas(metricType string) bool {
+	if c.aggregateDeltas {
+		return true
+	}
+	for _, prefix := range c.deltaCounterPrefixes {
+		if prefix != "" && strings.HasPrefix(metricType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregationConfig returns the aggregation applied to the time series of a metric descriptor, if any.
+func (c *MonitoringCollector) aggregationConfig(metricDescriptor *monitoring.MetricDescriptor, config *ScrapeConfig) *MetricAggregationConfig {
+	for _, target := range c.metricTargets {
+		if target.MetricType == metricDescriptor.Type && target.Aggregation != nil {
+			return target.Aggregation
+		}
+	}
+	for i, ef := range c.metricsAggregationConfigs {
+		if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
+			return &c.metricsAggregationConfigs[i]
+		}
+	}
+	for i, ef := range config.Aggregations {
+		if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
+			return &config.Aggregations[i]
+		}
+	}
+	return nil
 }
 
-func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metric, begun time.Time) error {
-	metricDescriptorsFunction := func(descriptors []*monitoring.MetricDescriptor) error {
+func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metric, begun time.Time, state *scrapeState) error {
+	// Each descriptor holds a slot of the prefix semaphore, if any, then of the collector semaphore. The slots are taken
+	// before starting the goroutine of a descriptor so that the goroutines are bounded as well as the requests.
+	metricDescriptorsFunction := func(descriptors []*monitoring.MetricDescriptor, prefixConcurrency semaphore) error {
 		var wg = &sync.WaitGroup{}
 
 		// It has been noticed that the same metric descriptor can be obtained from different GCP
@@ -281,10 +1206,7 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 		// can filter descriptors to keep just one per type.
 		//
 		// The following makes sure metric descriptors are unique to avoid fetching more than once
-		uniqueDescriptors := make(map[string]*monitoring.MetricDescriptor)
-		for _, descriptor := range descriptors {
-			uniqueDescriptors[descriptor.Type] = descriptor
-		}
+		uniqueDescriptors := c.uniqueDescriptors(descriptors)
 
 		errChannel := make(chan error, len(uniqueDescriptors))
 
@@ -292,76 +1214,26 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 		startTime := endTime.Add(c.metricsInterval * -1)
 
 		for _, metricDescriptor := range uniqueDescriptors {
+			state.addDescriptor(metricDescriptor)
+			prefixConcurrency.acquire()
+			c.concurrency.acquire()
 			wg.Add(1)
 			go func(metricDescriptor *monitoring.MetricDescriptor, ch chan<- prometheus.Metric, startTime, endTime time.Time) {
 				defer wg.Done()
-				c.logger.Debug("retrieving Google Stackdriver Monitoring metrics for descriptor", "descriptor", metricDescriptor.Type)
-				filter := fmt.Sprintf("metric.type=\"%s\"", metricDescriptor.Type)
-				if c.monitoringDropDelegatedProjects {
-					filter = fmt.Sprintf(
-						"project=\"%s\" AND metric.type=\"%s\"",
-						c.projectID,
-						metricDescriptor.Type)
-				}
-
-				if c.metricsIngestDelay &&
-					metricDescriptor.Metadata != nil &&
-					metricDescriptor.Metadata.IngestDelay != "" {
-					ingestDelay := metricDescriptor.Metadata.IngestDelay
-					ingestDelayDuration, err := time.ParseDuration(ingestDelay)
-					if err != nil {
-						c.logger.Error("error parsing ingest delay from metric metadata", "descriptor", metricDescriptor.Type, "err", err, "delay", ingestDelay)
-						errChannel <- err
-						return
-					}
-					c.logger.Debug("adding ingest delay", "descriptor", metricDescriptor.Type, "delay", ingestDelay)
-					endTime = endTime.Add(ingestDelayDuration * -1)
-					startTime = startTime.Add(ingestDelayDuration * -1)
-				}
+				defer prefixConcurrency.release()
+				defer c.concurrency.release()
 
-				for _, ef := range c.metricsFilters {
-					if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
-						filter = fmt.Sprintf("%s AND (%s)", filter, ef.FilterQuery)
-					}
-				}
-
-				c.logger.Debug("retrieving Google Stackdriver Monitoring metrics with filter", "filter", filter)
-
-				timeSeriesListCall := c.monitoringService.Projects.TimeSeries.List(utils.ProjectResource(c.projectID)).
-					Filter(filter).
-					IntervalStartTime(startTime.Format(time.RFC3339Nano)).
-					IntervalEndTime(endTime.Format(time.RFC3339Nano))
-
-				for _, ef := range c.metricsAggregationConfigs {
-					if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
-						timeSeriesListCall.AggregationAlignmentPeriod(ef.AlignmentPeriod).
-							AggregationCrossSeriesReducer(ef.CrossSeriesReducer).
-							AggregationGroupByFields(ef.GroupByFields...).
-							AggregationPerSeriesAligner(ef.PerSeriesAligner)
-						break
-					}
+				err := c.collectTimeSeries(metricDescriptor, ch, startTime, endTime, begun, state)
+				if err != nil {
+					state.addError()
+					errChannel <- err
 				}
-
-				for {
-					c.apiCallsTotalMetric.Inc()
-					page, err := timeSeriesListCall.Do()
+				if c.descriptorScrapeErrorMetric != nil {
+					descriptorError := float64(0)
 					if err != nil {
-						c.logger.Error("error retrieving Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
-						errChannel <- err
-						break
-					}
-					if page == nil {
-						break
-					}
-					if err := c.reportTimeSeriesMetrics(page, metricDescriptor, ch, begun); err != nil {
-						c.logger.Error("error reporting Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
-						errChannel <- err
-						break
-					}
-					if page.NextPageToken == "" {
-						break
+						descriptorError = float64(1)
 					}
-					timeSeriesListCall.PageToken(page.NextPageToken)
+					c.descriptorScrapeErrorMetric.WithLabelValues(metricDescriptor.Type).Set(descriptorError)
 				}
 			}(metricDescriptor, ch, startTime, endTime)
 		}
@@ -372,41 +1244,66 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 		return <-errChannel
 	}
 
+	// The descriptors listed for the prefixes are filtered by the predicate, the explicit targets are not
+	listedDescriptorsFunction := func(descriptors []*monitoring.MetricDescriptor, prefixConcurrency semaphore) error {
+		var matching []*monitoring.MetricDescriptor
+		for _, descriptor := range descriptors {
+			if c.descriptorPredicate.Matches(descriptor) {
+				matching = append(matching, descriptor)
+			} else {
+				c.logger.Debug("discarding metric descriptor not matching the predicate", "descriptor", descriptor.Type)
+			}
+		}
+		return metricDescriptorsFunction(matching, prefixConcurrency)
+	}
+
 	var wg = &sync.WaitGroup{}
 
-	errChannel := make(chan error, len(c.metricsTypePrefixes))
+	errChannel := make(chan error, len(c.metricsTypePrefixes)+2)
+
+	// Explicit targets are scraped with their own descriptors, no metric descriptors listing is required.
+	coveredTargets, targetDescriptors := c.targetDescriptors()
+	if len(targetDescriptors) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.logger.Debug("retrieving Google Stackdriver Monitoring metrics for explicit targets", "targets", len(targetDescriptors))
+			if err := metricDescriptorsFunction(targetDescriptors, nil); err != nil {
+				errChannel <- err
+			}
+		}()
+	}
 
 	for _, metricsTypePrefix := range c.metricsTypePrefixes {
 		wg.Add(1)
 		go func(metricsTypePrefix string) {
 			defer wg.Done()
-			ctx := context.Background()
-			filter := fmt.Sprintf("metric.type = starts_with(\"%s\")", metricsTypePrefix)
-			if c.monitoringDropDelegatedProjects {
-				filter = fmt.Sprintf(
-					"project = \"%s\" AND metric.type = starts_with(\"%s\")",
-					c.projectID,
-					metricsTypePrefix)
+
+			prefixConcurrency := newSemaphore(c.prefixConcurrency[metricsTypePrefix])
+			var pageErr error
+			pageFunction := func(descriptors []*monitoring.MetricDescriptor) error {
+				pageErr = listedDescriptorsFunction(descriptors, prefixConcurrency)
+				return pageErr
 			}
 
 			if cached := c.descriptorCache.Lookup(metricsTypePrefix); cached != nil {
 				c.logger.Debug("using cached Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
-				if err := metricDescriptorsFunction(cached); err != nil {
+				if err := pageFunction(cached); err != nil {
 					errChannel <- err
 				}
-			} else {
-				var cache []*monitoring.MetricDescriptor
-
-				callback := func(r *monitoring.ListMetricDescriptorsResponse) error {
-					c.apiCallsTotalMetric.Inc()
-					cache = append(cache, r.MetricDescriptors...)
-					return metricDescriptorsFunction(r.MetricDescriptors)
+			} else if stale := c.lookupStaleDescriptors(metricsTypePrefix); stale != nil {
+				c.logger.Debug("using stale cached Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
+				c.refreshDescriptorCache(metricsTypePrefix)
+				if err := pageFunction(stale); err != nil {
+					errChannel <- err
 				}
-
-				c.logger.Debug("listing Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
-				if err := c.monitoringService.Projects.MetricDescriptors.List(utils.ProjectResource(c.projectID)).
-					Filter(filter).
-					Pages(ctx, callback); err != nil {
+			} else {
+				cache, err := c.listMetricDescriptors(metricsTypePrefix, pageFunction, state)
+				if err != nil {
+					// The errors of the time series of a page are already recorded
+					if err != pageErr {
+						state.addError()
+					}
 					errChannel <- err
 				}
 
@@ -416,64 +1313,281 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 	}
 
 	wg.Wait()
+
+	// The explicit targets covered by a prefix are only scraped when the prefix did not keep their descriptor, e.g. as
+	// it did not match the descriptor predicate.
+	var missingTargets []*monitoring.MetricDescriptor
+	for _, descriptor := range coveredTargets {
+		if state.hasDescriptor(descriptor.Type) {
+			c.logger.Debug("explicit target already covered by a metric type prefix", "metric_type", descriptor.Type)
+			continue
+		}
+		missingTargets = append(missingTargets, descriptor)
+	}
+	if len(missingTargets) > 0 {
+		c.logger.Debug("retrieving Google Stackdriver Monitoring metrics for explicit targets not kept by their prefix", "targets", len(missingTargets))
+		if err := metricDescriptorsFunction(missingTargets, nil); err != nil {
+			errChannel <- err
+		}
+	}
 	close(errChannel)
 
 	c.logger.Debug("Done reporting monitoring metrics")
 	return <-errChannel
 }
 
+// listMetricDescriptors lists all the metric descriptors starting with a prefix, calling pageFunction for each page.
+// The API calls are recorded in the state of the scrape, if any.
+func (c *MonitoringCollector) listMetricDescriptors(metricsTypePrefix string, pageFunction func([]*monitoring.MetricDescriptor) error, state *scrapeState) ([]*monitoring.MetricDescriptor, error) {
+	ctx := context.Background()
+	filter := fmt.Sprintf("metric.type = starts_with(\"%s\")", metricsTypePrefix)
+	if c.monitoringDropDelegatedProjects {
+		filter = fmt.Sprintf(
+			"project = \"%s\" AND metric.type = starts_with(\"%s\")",
+			c.projectID,
+			metricsTypePrefix)
+	}
+
+	var descriptors []*monitoring.MetricDescriptor
+
+	// The call of each page is in progress until its callback, which scrapes the descriptors of the page
+	done := c.inFlightCalls.start()
+	callback := func(r *monitoring.ListMetricDescriptorsResponse) error {
+		done()
+		defer func() { done = c.inFlightCalls.start() }()
+		c.apiCallsTotalMetric.Inc()
+		state.addAPICall()
+		descriptors = append(descriptors, r.MetricDescriptors...)
+		if pageFunction == nil {
+			return nil
+		}
+		return pageFunction(r.MetricDescriptors)
+	}
+
+	c.logger.Debug("listing Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
+	err := c.monitoringService.Projects.MetricDescriptors.List(utils.ProjectResource(c.projectID)).
+		Filter(filter).
+		Pages(ctx, callback)
+	done()
+	return descriptors, err
+}
+
+// lookupStaleDescriptors returns the expired cached metric descriptors of a prefix when background refresh is enabled.
+func (c *MonitoringCollector) lookupStaleDescriptors(metricsTypePrefix string) []*monitoring.MetricDescriptor {
+	if !c.descriptorCacheRefresh {
+		return nil
+	}
+	cache, ok := c.descriptorCache.(staleDescriptorCache)
+	if !ok {
+		return nil
+	}
+	descriptors, _ := cache.LookupStale(metricsTypePrefix)
+	return descriptors
+}
+
+// refreshDescriptorCache lists the metric descriptors of a prefix in the background and stores them in the cache. The
+// stale entry is kept when the refresh fails so it is retried on the next scrape.
+func (c *MonitoringCollector) refreshDescriptorCache(metricsTypePrefix string) {
+	if _, refreshing := c.descriptorCacheRefreshing.LoadOrStore(metricsTypePrefix, true); refreshing {
+		return
+	}
+
+	go func() {
+		defer c.descriptorCacheRefreshing.Delete(metricsTypePrefix)
+
+		descriptors, err := c.listMetricDescriptors(metricsTypePrefix, nil, nil)
+		if err != nil {
+			c.descriptorCacheRefreshErrors.WithLabelValues(prefixKind(metricsTypePrefix)).Inc()
+			c.logger.Error("error refreshing Google Stackdriver Monitoring metric descriptors cache", "prefix", metricsTypePrefix, "err", err)
+			return
+		}
+		c.descriptorCache.Store(metricsTypePrefix, descriptors)
+	}()
+}
+
+// collectTimeSeries retrieves all the time series pages for a single metric descriptor and reports them.
+func (c *MonitoringCollector) collectTimeSeries(metricDescriptor *monitoring.MetricDescriptor, ch chan<- prometheus.Metric, startTime, endTime time.Time, begun time.Time, state *scrapeState) error {
+	c.logger.Debug("retrieving Google Stackdriver Monitoring metrics for descriptor", "descriptor", metricDescriptor.Type)
+	config := state.scrapeConfig()
+	filter := c.timeSeriesFilter(metricDescriptor, config)
+
+	if c.metricsIngestDelay &&
+		metricDescriptor.Metadata != nil &&
+		metricDescriptor.Metadata.IngestDelay != "" {
+		ingestDelay := metricDescriptor.Metadata.IngestDelay
+		ingestDelayDuration, err := time.ParseDuration(ingestDelay)
+		if err != nil {
+			c.logger.Error("error parsing ingest delay from metric metadata", "descriptor", metricDescriptor.Type, "err", err, "delay", ingestDelay)
+			return err
+		}
+		c.logger.Debug("adding ingest delay", "descriptor", metricDescriptor.Type, "delay", ingestDelay)
+		endTime = endTime.Add(ingestDelayDuration * -1)
+		startTime = startTime.Add(ingestDelayDuration * -1)
+	}
+
+	c.logger.Debug("retrieving Google Stackdriver Monitoring metrics with filter", "filter", filter)
+
+	timeSeriesListCall := c.monitoringService.Projects.TimeSeries.List(utils.ProjectResource(c.projectID)).
+		Filter(filter).
+		IntervalStartTime(startTime.Format(time.RFC3339Nano)).
+		IntervalEndTime(endTime.Format(time.RFC3339Nano))
+
+	// The alignment period is only tuned for the metric types without aggregation config whose metric kind and value type
+	// have an aligner keeping their type
+	var tuned bool
+	if ef := c.aggregationConfig(metricDescriptor, config); ef != nil {
+		perSeriesAligner := ef.PerSeriesAligner
+		if perSeriesAligner == "" {
+			perSeriesAligner = c.valueTypeAligner(metricDescriptor)
+		}
+		timeSeriesListCall.AggregationAlignmentPeriod(ef.AlignmentPeriod).
+			AggregationCrossSeriesReducer(ef.CrossSeriesReducer).
+			AggregationGroupByFields(ef.GroupByFields...).
+			AggregationPerSeriesAligner(perSeriesAligner)
+	} else if aligner := c.valueTypeAligner(metricDescriptor); c.alignmentTuner != nil &&
+		tunableAligner(metricDescriptor.MetricKind, metricDescriptor.ValueType, aligner) {
+		tuned = true
+		if period := c.alignmentTuner.period(metricDescriptor.Type); period > 0 {
+			timeSeriesListCall.AggregationAlignmentPeriod(fmt.Sprintf("%ds", int64(period.Seconds()))).
+				AggregationPerSeriesAligner(aligner)
+		}
+	}
+
+	var maxPoints int
+	for {
+		c.apiCallsTotalMetric.Inc()
+		state.addAPICall()
+		done := c.inFlightCalls.start()
+		page, err := timeSeriesListCall.Do()
+		done()
+		if c.missingDescriptorsTotalMetric != nil && isNotFound(err) {
+			// The metric type was deleted since its descriptor was listed
+			c.logger.Debug("skipping metric descriptor not found", "descriptor", metricDescriptor.Type, "err", err)
+			c.missingDescriptorsTotalMetric.WithLabelValues(metricDescriptor.Type).Inc()
+			return nil
+		}
+		if err != nil {
+			c.logger.Error("error retrieving Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
+			return err
+		}
+		if page == nil {
+			break
+		}
+		c.apiPagesTotalMetric.Inc()
+		for _, timeSeries := range page.TimeSeries {
+			maxPoints = max(maxPoints, len(timeSeries.Points))
+		}
+		if err := c.reportTimeSeriesMetrics(page, metricDescriptor, ch, begun, state); err != nil {
+			c.logger.Error("error reporting Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
+			return err
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		timeSeriesListCall.PageToken(page.NextPageToken)
+	}
+
+	if tuned {
+		if period, increased := c.alignmentTuner.observe(metricDescriptor.Type, maxPoints); increased {
+			c.logger.Info("increasing the alignment period of a metric type returning too many points per series",
+				"descriptor", metricDescriptor.Type, "points", maxPoints, "alignment_period", period)
+		}
+	}
+	return nil
+}
+
+// isNotFound returns whether an error of the Monitoring API is a not found error.
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// timeSeriesFilter returns the filter used to list the time series of a metric descriptor.
+func (c *MonitoringCollector) timeSeriesFilter(metricDescriptor *monitoring.MetricDescriptor, config *ScrapeConfig) string {
+	filter := fmt.Sprintf("metric.type=\"%s\"", metricDescriptor.Type)
+	if c.monitoringDropDelegatedProjects {
+		filter = fmt.Sprintf(
+			"project=\"%s\" AND metric.type=\"%s\"",
+			c.projectID,
+			metricDescriptor.Type)
+	}
+
+	for _, filters := range [][]MetricFilter{c.metricsFilters, config.ExtraFilters} {
+		for _, ef := range filters {
+			if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
+				filter = fmt.Sprintf("%s AND (%s)", filter, ef.FilterQuery)
+			}
+		}
+	}
+
+	// Sort the resource labels to always produce the same filter
+	resourceLabelKeys := make([]string, 0, len(c.resourceLabelFilters))
+	for key := range c.resourceLabelFilters {
+		resourceLabelKeys = append(resourceLabelKeys, key)
+	}
+	sort.Strings(resourceLabelKeys)
+	for _, key := range resourceLabelKeys {
+		filter = fmt.Sprintf("%s AND resource.labels.%s = %q", filter, key, c.resourceLabelFilters[key])
+	}
+
+	return filter
+}
+
 func (c *MonitoringCollector) reportTimeSeriesMetrics(
 	page *monitoring.ListTimeSeriesResponse,
 	metricDescriptor *monitoring.MetricDescriptor,
 	ch chan<- prometheus.Metric,
 	begun time.Time,
+	state *scrapeState,
 ) error {
 	var metricValue float64
+	var reportedSeries, samples int
 	var metricValueType prometheus.ValueType
 	var newestTSPoint *monitoring.Point
+	aggregateDeltas := c.aggregatesDeltas(metricDescriptor.Type)
 
 	timeSeriesMetrics, err := newTimeSeriesMetrics(metricDescriptor,
 		ch,
 		c.collectorFillMissingLabels,
 		c.counterStore,
 		c.histogramStore,
-		c.aggregateDeltas,
+		aggregateDeltas,
+		c.createdTimestamps,
+		c.nameSuffixes,
+		// The families are only counted for the post scrape hook
+		c.postScrapeHook != nil,
 	)
 	if err != nil {
 		return fmt.Errorf("error creating the TimeSeriesMetrics %v", err)
 	}
+	// The metrics sent before an error are counted as well
+	if timeSeriesMetrics.families != nil {
+		defer func() { state.addFamilies(timeSeriesMetrics.families) }()
+	}
 	for _, timeSeries := range page.TimeSeries {
-		newestEndTime := time.Unix(0, 0)
-		for _, point := range timeSeries.Points {
-			endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
-			if err != nil {
-				return fmt.Errorf("Error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
-			}
-			if endTime.After(newestEndTime) {
-				newestEndTime = endTime
-				newestTSPoint = point
-			}
+		var newestEndTime time.Time
+		newestTSPoint, newestEndTime, err = c.newestPoint(timeSeries)
+		if err != nil {
+			return err
 		}
-		labelKeys := []string{"unit"}
-		labelValues := []string{metricDescriptor.Unit}
-
-		// Add the metric labels
-		// @see https://cloud.google.com/monitoring/api/metrics
-		for key, value := range timeSeries.Metric.Labels {
-			if !c.keyExists(labelKeys, key) {
-				labelKeys = append(labelKeys, key)
-				labelValues = append(labelValues, value)
-			}
+		if newestTSPoint == nil {
+			continue
 		}
-
-		// Add the monitored resource labels
-		// @see https://cloud.google.com/monitoring/api/resources
-		for key, value := range timeSeries.Resource.Labels {
-			if !c.keyExists(labelKeys, key) {
-				labelKeys = append(labelKeys, key)
-				labelValues = append(labelValues, value)
+		samples += len(timeSeries.Points)
+
+		// Clock skew or a misconfigured offset can produce points in the future which Prometheus may reject
+		if now := time.Now(); newestEndTime.After(now) {
+			c.futurePointsTotalMetric.WithLabelValues(metricDescriptor.Type).Inc()
+			switch c.futurePoints {
+			case FuturePointsDrop:
+				c.logger.Debug("discarding time series with a point in the future", "metric", timeSeries.Metric.Type, "end_time", newestEndTime)
+				continue
+			case FuturePointsClamp:
+				c.logger.Debug("clamping point in the future to the current time", "metric", timeSeries.Metric.Type, "end_time", newestEndTime)
+				newestEndTime = now
 			}
 		}
+		labelKeys, labelValues := c.seriesLabels(metricDescriptor, timeSeries)
 
 		// Add the monitored system labels
 		var systemLabels map[string]string
@@ -502,24 +1616,24 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 			}
 
 			if dropDelegatedProject {
+				c.delegatedSeriesDroppedMetric.WithLabelValues(metricDescriptor.Type).Inc()
 				continue
 			}
 		}
 
-		switch timeSeries.MetricKind {
-		case "GAUGE":
-			metricValueType = prometheus.GaugeValue
-		case "DELTA":
-			if c.aggregateDeltas {
-				metricValueType = prometheus.CounterValue
-			} else {
-				metricValueType = prometheus.GaugeValue
-			}
-		case "CUMULATIVE":
-			metricValueType = prometheus.CounterValue
-		default:
+		valueType, ok := c.metricTypePolicy.valueType(timeSeries.MetricKind, aggregateDeltas)
+		if !ok {
 			continue
 		}
+		metricValueType = valueType
+
+		// The last seen time is only reported along with the series
+		reported := func() {
+			reportedSeries++
+			if c.lastSeenMetrics {
+				timeSeriesMetrics.CollectLastSeen(timeSeries, newestEndTime, labelKeys, labelValues)
+			}
+		}
 
 		switch timeSeries.ValueType {
 		case "BOOL":
@@ -533,13 +1647,50 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 			metricValue = *newestTSPoint.Value.DoubleValue
 		case "DISTRIBUTION":
 			dist := newestTSPoint.Value.DistributionValue
+			if hasEmptyExplicitBuckets(dist) {
+				// An explicit bucket without bounds only has a single +Inf bucket which is most likely malformed data
+				c.emptyExplicitBucketsTotalMetric.WithLabelValues(metricDescriptor.Type).Inc()
+				if c.strictExplicitBuckets {
+					c.logger.Debug("discarding distribution with empty explicit bucket bounds", "resource", timeSeries.Resource.Type, "metric",
+						timeSeries.Metric.Type)
+					continue
+				}
+				c.logger.Debug("distribution has empty explicit bucket bounds", "resource", timeSeries.Resource.Type, "metric",
+					timeSeries.Metric.Type)
+			}
+			if c.reportsNativeHistogram(dist, timeSeries.MetricKind, aggregateDeltas) {
+				growthFactor := dist.BucketOptions.ExponentialBuckets.GrowthFactor
+				schema, exact, err := nativeHistogramSchema(growthFactor)
+				if err == nil {
+					if !exact {
+						c.logger.Debug("approximating exponential buckets with the closest native histogram schema", "metric",
+							timeSeries.Metric.Type, "growth_factor", growthFactor, "schema", schema)
+					}
+					err = timeSeriesMetrics.CollectNativeHistogram(timeSeries, newestEndTime, labelKeys, dist, schema, labelValues)
+				}
+				if err == nil {
+					reported()
+					continue
+				}
+				c.logger.Debug("reporting distribution as a classic histogram", "resource", timeSeries.Resource.Type, "metric",
+					timeSeries.Metric.Type, "err", err)
+			}
 			buckets, err := c.generateHistogramBuckets(dist)
 
 			if err == nil {
 				timeSeriesMetrics.CollectNewConstHistogram(timeSeries, newestEndTime, labelKeys, dist, buckets, labelValues, timeSeries.MetricKind)
+				reported()
 			} else {
-				c.logger.Debug("discarding", "resource", timeSeries.Resource.Type, "metric",
-					timeSeries.Metric.Type, "err", err)
+				c.histogramErrorsTotalMetric.WithLabelValues(metricDescriptor.Type).Inc()
+				if c.distributionFallback {
+					c.logger.Debug("reporting distribution count and sum only", "resource", timeSeries.Resource.Type, "metric",
+						timeSeries.Metric.Type, "err", err)
+					timeSeriesMetrics.CollectDistributionFallback(timeSeries, newestEndTime, labelKeys, metricValueType, dist, labelValues, timeSeries.MetricKind)
+					reported()
+				} else {
+					c.logger.Debug("discarding", "resource", timeSeries.Resource.Type, "metric",
+						timeSeries.Metric.Type, "err", err)
+				}
 			}
 			continue
 		default:
@@ -547,12 +1698,123 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 			continue
 		}
 
+		if timeSeries.MetricKind == "GAUGE" && c.isGaugeCounter(metricDescriptor.Type) {
+			key := gaugeCounterKey(buildFQName(timeSeries), labelKeys, labelValues)
+			if increment, ok := c.gaugeCounters.increment(key, metricValue, newestEndTime); ok {
+				timeSeriesMetrics.CollectGaugeIncrement(timeSeries, newestEndTime, labelKeys, increment, labelValues)
+			}
+			reported()
+			continue
+		}
+
 		timeSeriesMetrics.CollectNewConstMetric(timeSeries, newestEndTime, labelKeys, metricValueType, metricValue, labelValues, timeSeries.MetricKind)
+		reported()
 	}
+	if reportedSeries > 0 {
+		state.addMetricType(metricDescriptor.Type)
+	}
+	state.addSeries(reportedSeries, samples)
 	timeSeriesMetrics.Complete(begun)
 	return nil
 }
 
+// seriesLabels returns the unit, metric and monitored resource label keys and values of a time series, without the
+// duplicate keys. The resolved order of the keys is cached by metric type with the label layout cache.
+func (c *MonitoringCollector) seriesLabels(metricDescriptor *monitoring.MetricDescriptor, timeSeries *monitoring.TimeSeries) ([]string, []string) {
+	if layout := c.labelLayouts.lookup(metricDescriptor.Type); layout != nil && layout.matches(timeSeries) {
+		return layout.labels(metricDescriptor.Unit, timeSeries)
+	}
+
+	labelKeys := []string{"unit"}
+	labelValues := []string{metricDescriptor.Unit}
+	layout := &labelLayout{resourceType: timeSeries.Resource.Type}
+
+	// Add the metric labels
+	// @see https://cloud.google.com/monitoring/api/metrics
+	for key, value := range timeSeries.Metric.Labels {
+		layout.metricKeys = append(layout.metricKeys, key)
+		if !c.keyExists(labelKeys, key) {
+			layout.keptMetricKeys = append(layout.keptMetricKeys, key)
+			labelKeys = append(labelKeys, key)
+			labelValues = append(labelValues, value)
+		}
+	}
+
+	// Add the monitored resource labels
+	// @see https://cloud.google.com/monitoring/api/resources
+	for rawKey, value := range timeSeries.Resource.Labels {
+		layout.resourceKeys = append(layout.resourceKeys, rawKey)
+		key := c.resourceLabelNames.labelKey(timeSeries.Resource.Type, rawKey)
+		if !c.keyExists(labelKeys, key) {
+			layout.keptResourceKeys = append(layout.keptResourceKeys, rawKey)
+			labelKeys = append(labelKeys, key)
+			labelValues = append(labelValues, value)
+		}
+	}
+
+	if c.labelLayouts != nil {
+		layout.labelKeys = append([]string{}, labelKeys...)
+		c.labelLayouts.store(metricDescriptor.Type, layout)
+	}
+	return labelKeys, labelValues
+}
+
+// newestPoint returns the point of a time series with the newest end time, or nil if it has no points. Ties are broken
+// according to the newest point ties policy.
+func (c *MonitoringCollector) newestPoint(timeSeries *monitoring.TimeSeries) (*monitoring.Point, time.Time, error) {
+	newestEndTime := time.Unix(0, 0)
+	var newest []*monitoring.Point
+	for _, point := range timeSeries.Points {
+		endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+		if err != nil {
+			if c.invalidPointsTotalMetric == nil {
+				return nil, newestEndTime, fmt.Errorf("Error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
+			}
+			c.logger.Debug("skipping point with an invalid end time", "metric", timeSeries.Metric.Type, "end_time", point.Interval.EndTime, "err", err)
+			c.invalidPointsTotalMetric.WithLabelValues(timeSeries.Metric.Type).Inc()
+			continue
+		}
+		switch {
+		case endTime.After(newestEndTime):
+			newestEndTime = endTime
+			newest = []*monitoring.Point{point}
+		case len(newest) > 0 && endTime.Equal(newestEndTime):
+			newest = append(newest, point)
+		}
+	}
+
+	if len(newest) == 0 {
+		return nil, newestEndTime, nil
+	}
+	if len(newest) > 1 {
+		c.logger.Debug("several points share the newest end time", "metric", timeSeries.Metric.Type, "points", len(newest), "policy", c.newestPointTies)
+	}
+
+	switch c.newestPointTies {
+	case NewestPointLast:
+		return newest[len(newest)-1], newestEndTime, nil
+	case NewestPointSum:
+		if len(newest) == 1 || timeSeries.MetricKind != "DELTA" {
+			break
+		}
+		switch timeSeries.ValueType {
+		case "INT64":
+			var sum int64
+			for _, point := range newest {
+				sum += *point.Value.Int64Value
+			}
+			return &monitoring.Point{Interval: newest[0].Interval, Value: &monitoring.TypedValue{Int64Value: &sum}}, newestEndTime, nil
+		case "DOUBLE":
+			var sum float64
+			for _, point := range newest {
+				sum += *point.Value.DoubleValue
+			}
+			return &monitoring.Point{Interval: newest[0].Interval, Value: &monitoring.TypedValue{DoubleValue: &sum}}, newestEndTime, nil
+		}
+	}
+	return newest[0], newestEndTime, nil
+}
+
 func (c *MonitoringCollector) generateHistogramBuckets(
 	dist *monitoring.Distribution,
 ) (map[float64]uint64, error) {
@@ -604,6 +1866,21 @@ func (c *MonitoringCollector) generateHistogramBuckets(
 	return buckets, nil
 }
 
+// reportsNativeHistogram returns whether a distribution is reported as a native histogram.
+func (c *MonitoringCollector) reportsNativeHistogram(dist *monitoring.Distribution, metricKind string, aggregateDeltas bool) bool {
+	return c.nativeHistograms &&
+		dist.BucketOptions != nil &&
+		dist.BucketOptions.ExponentialBuckets != nil &&
+		!c.collectorFillMissingLabels &&
+		!(metricKind == "DELTA" && aggregateDeltas)
+}
+
+func hasEmptyExplicitBuckets(dist *monitoring.Distribution) bool {
+	return dist.BucketOptions != nil &&
+		dist.BucketOptions.ExplicitBuckets != nil &&
+		len(dist.BucketOptions.ExplicitBuckets.Bounds) == 0
+}
+
 func (c *MonitoringCollector) keyExists(labelKeys []string, key string) bool {
 	for _, item := range labelKeys {
 		if item == key {
@@ -14,12 +14,32 @@
 package collectors
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
 )
 
 func TestIsGoogleMetric(t *testing.T) {
@@ -172,6 +192,28 @@ func TestNewMonitoringCollector(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:      "collector with invalid alignment period",
+			projectID: "test-project",
+			opts: MonitoringCollectorOptions{
+				MetricTypePrefixes:       []string{"pubsub.googleapis.com"},
+				MetricAggregationConfigs: []MetricAggregationConfig{{TargetedMetricPrefix: "pubsub.googleapis.com", AlignmentPeriod: "1m"}},
+				RequestInterval:          5 * time.Minute,
+			},
+			expectError: true,
+		},
+		{
+			name:      "collector with invalid explicit target alignment period",
+			projectID: "test-project",
+			opts: MonitoringCollectorOptions{
+				ExplicitTargets: []MetricTarget{{
+					MetricType:  "pubsub.googleapis.com/subscription/num_undelivered_messages",
+					Aggregation: &MetricAggregationConfig{AlignmentPeriod: "sixty"},
+				}},
+				RequestInterval: 5 * time.Minute,
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -273,7 +315,7 @@ func TestMonitoringCollectorDescribe(t *testing.T) {
 	}
 
 	// Create a channel to collect descriptions
-	ch := make(chan *prometheus.Desc, 10)
+	ch := make(chan *prometheus.Desc, 20)
 
 	// Call Describe
 	collector.Describe(ch)
@@ -285,10 +327,2396 @@ func TestMonitoringCollectorDescribe(t *testing.T) {
 		count++
 	}
 
-	// Should have 6 metrics: api_calls_total, scrapes_total, scrape_errors_total,
-	// last_scrape_error, last_scrape_timestamp, last_scrape_duration_seconds
-	expectedCount := 6
+	// Should have 13 metrics: api_calls_total, scrapes_total, scrape_errors_total,
+	// last_scrape_error, last_scrape_timestamp, last_scrape_duration_seconds,
+	// empty_explicit_buckets_total, future_points_total, histogram_errors_total,
+	// unit_conflicts_total, metric_types_scraped, overlapping_scrapes_total, api_pages_total
+	expectedCount := 13
 	if count != expectedCount {
 		t.Errorf("Expected %d metric descriptions, got %d", expectedCount, count)
 	}
 }
+
+// fakeMonitoringAPI is a minimal in-memory implementation of the Google Monitoring API endpoints used by the collector.
+type fakeMonitoringAPI struct {
+	mu sync.Mutex
+
+	descriptors       []*monitoring.MetricDescriptor
+	resources         []*monitoring.MonitoredResourceDescriptor
+	resourcesStatus   int
+	timeSeries        map[string][]*monitoring.ListTimeSeriesResponse
+	timeSeriesStatus  map[string]int
+	descriptorsStatus int
+
+	timeSeriesRequests []url.Values
+	descriptorRequests int
+}
+
+var (
+	fakeMetricTypeRE   = regexp.MustCompile(`metric\.type\s*=\s*"([^"]+)"`)
+	fakeMetricPrefixRE = regexp.MustCompile(`starts_with\("([^"]+)"\)`)
+)
+
+func (f *fakeMonitoringAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	var response interface{}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/metricDescriptors"):
+		f.descriptorRequests++
+		if f.descriptorsStatus != 0 {
+			http.Error(w, `{"error": {"message": "fake descriptors error"}}`, f.descriptorsStatus)
+			return
+		}
+		var prefix string
+		if m := fakeMetricPrefixRE.FindStringSubmatch(query.Get("filter")); m != nil {
+			prefix = m[1]
+		}
+		descriptors := []*monitoring.MetricDescriptor{}
+		for _, descriptor := range f.descriptors {
+			if strings.HasPrefix(descriptor.Type, prefix) {
+				descriptors = append(descriptors, descriptor)
+			}
+		}
+		response = &monitoring.ListMetricDescriptorsResponse{MetricDescriptors: descriptors}
+	case strings.HasSuffix(r.URL.Path, "/monitoredResourceDescriptors"):
+		if f.resourcesStatus != 0 {
+			http.Error(w, `{"error": {"message": "fake resource descriptors error"}}`, f.resourcesStatus)
+			return
+		}
+		response = &monitoring.ListMonitoredResourceDescriptorsResponse{ResourceDescriptors: f.resources}
+	case strings.HasSuffix(r.URL.Path, "/timeSeries"):
+		f.timeSeriesRequests = append(f.timeSeriesRequests, query)
+		var metricType string
+		if m := fakeMetricTypeRE.FindStringSubmatch(query.Get("filter")); m != nil {
+			metricType = m[1]
+		}
+		if status := f.timeSeriesStatus[metricType]; status != 0 {
+			http.Error(w, `{"error": {"message": "fake time series error"}}`, status)
+			return
+		}
+		pages := f.timeSeries[metricType]
+		pageIndex := 0
+		if token := query.Get("pageToken"); token != "" {
+			pageIndex, _ = strconv.Atoi(token)
+		}
+		page := &monitoring.ListTimeSeriesResponse{}
+		if pageIndex < len(pages) {
+			copied := *pages[pageIndex]
+			page = &copied
+			if pageIndex+1 < len(pages) {
+				page.NextPageToken = strconv.Itoa(pageIndex + 1)
+			}
+		}
+		response = page
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func newFakeMonitoringService(t *testing.T, api http.Handler) *monitoring.Service {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	service, err := monitoring.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create monitoring service: %v", err)
+	}
+	return service
+}
+
+func newTestDescriptor(metricType, metricKind, valueType string) *monitoring.MetricDescriptor {
+	return &monitoring.MetricDescriptor{
+		Name:       "projects/test-project/metricDescriptors/" + metricType,
+		Type:       metricType,
+		MetricKind: metricKind,
+		ValueType:  valueType,
+	}
+}
+
+func newTestTimeSeries(metricType, metricKind string, value float64, endTime time.Time) *monitoring.TimeSeries {
+	return &monitoring.TimeSeries{
+		Metric:     &monitoring.Metric{Type: metricType, Labels: map[string]string{}},
+		Resource:   &monitoring.MonitoredResource{Type: "gce_instance", Labels: map[string]string{"project_id": "test-project"}},
+		MetricKind: metricKind,
+		ValueType:  "DOUBLE",
+		Points: []*monitoring.Point{
+			{
+				Interval: &monitoring.TimeInterval{EndTime: endTime.Format(time.RFC3339Nano)},
+				Value:    &monitoring.TypedValue{DoubleValue: &value},
+			},
+		},
+	}
+}
+
+func gatherFamilies(t *testing.T, collector prometheus.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	result := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		result[family.GetName()] = family
+	}
+	return result
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestDescriptorScrapeErrors(t *testing.T) {
+	now := time.Now()
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/ok", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/broken", "GAUGE", "DOUBLE"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/ok": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/ok", "GAUGE", 1, now)}}},
+		},
+		timeSeriesStatus: map[string]int{"custom.googleapis.com/broken": http.StatusInternalServerError},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:     []string{"custom.googleapis.com"},
+		RequestInterval:        5 * time.Minute,
+		DescriptorScrapeErrors: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+
+	family, ok := families["stackdriver_monitoring_descriptor_scrape_error"]
+	if !ok {
+		t.Fatal("Expected stackdriver_monitoring_descriptor_scrape_error to be reported")
+	}
+	if len(family.GetMetric()) != 2 {
+		t.Fatalf("Expected 2 descriptor scrape error series, got %d", len(family.GetMetric()))
+	}
+	expected := map[string]float64{
+		"custom.googleapis.com/ok":     0,
+		"custom.googleapis.com/broken": 1,
+	}
+	for _, metric := range family.GetMetric() {
+		metricType := labelValue(metric, "metric_type")
+		if want, ok := expected[metricType]; !ok || metric.GetGauge().GetValue() != want {
+			t.Errorf("Unexpected descriptor scrape error for %q: %v", metricType, metric.GetGauge().GetValue())
+		}
+	}
+
+	if got := families["stackdriver_monitoring_last_scrape_error"].GetMetric()[0].GetGauge().GetValue(); got != 1 {
+		t.Errorf("Expected last scrape error to be 1, got %v", got)
+	}
+}
+
+func TestAPIPagesTotal(t *testing.T) {
+	now := time.Now()
+	pages := func(metricType string, count int) []*monitoring.ListTimeSeriesResponse {
+		var pages []*monitoring.ListTimeSeriesResponse
+		for i := 0; i < count; i++ {
+			timeSeries := newTestTimeSeries(metricType, "GAUGE", 1, now)
+			timeSeries.Metric.Labels["page"] = strconv.Itoa(i)
+			pages = append(pages, &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{timeSeries}})
+		}
+		return pages
+	}
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/b", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/c", "GAUGE", "DOUBLE"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/a": pages("custom.googleapis.com/a", 3),
+			"custom.googleapis.com/b": pages("custom.googleapis.com/b", 2),
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	for scrape := 1; scrape <= 2; scrape++ {
+		families := gatherFamilies(t, collector)
+
+		// The metric type without time series still fetches an empty page, the descriptors page is not counted
+		expected := float64(6 * scrape)
+		if got := families["stackdriver_monitoring_api_pages_total"].GetMetric()[0].GetCounter().GetValue(); got != expected {
+			t.Errorf("Scrape %d: expected %v time series pages, got %v", scrape, expected, got)
+		}
+		if got := len(api.timeSeriesRequests); float64(got) != expected {
+			t.Errorf("Scrape %d: expected %v time series requests, got %d", scrape, expected, got)
+		}
+	}
+}
+
+func TestSkipMissingDescriptors(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(strconv.FormatBool(skip), func(t *testing.T) {
+			api := &fakeMonitoringAPI{
+				descriptors: []*monitoring.MetricDescriptor{
+					newTestDescriptor("custom.googleapis.com/ok", "GAUGE", "DOUBLE"),
+					newTestDescriptor("custom.googleapis.com/deleted", "GAUGE", "DOUBLE"),
+				},
+				timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+					"custom.googleapis.com/ok": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/ok", "GAUGE", 1, time.Now())}}},
+				},
+				timeSeriesStatus: map[string]int{"custom.googleapis.com/deleted": http.StatusNotFound},
+			}
+
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes:     []string{"custom.googleapis.com"},
+				RequestInterval:        5 * time.Minute,
+				SkipMissingDescriptors: skip,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			families := gatherFamilies(t, collector)
+
+			if _, ok := families["stackdriver_gce_instance_custom_googleapis_com_ok"]; !ok {
+				t.Error("Expected the metric types found to be reported")
+			}
+			expectedError := float64(1)
+			if skip {
+				expectedError = 0
+			}
+			if got := families["stackdriver_monitoring_last_scrape_error"].GetMetric()[0].GetGauge().GetValue(); got != expectedError {
+				t.Errorf("Expected last scrape error to be %v, got %v", expectedError, got)
+			}
+
+			family, ok := families["stackdriver_monitoring_missing_descriptors_total"]
+			if !skip {
+				if ok {
+					t.Error("Expected stackdriver_monitoring_missing_descriptors_total not to be reported")
+				}
+				return
+			}
+			if !ok || len(family.GetMetric()) != 1 {
+				t.Fatalf("Expected 1 stackdriver_monitoring_missing_descriptors_total series, got %v", family)
+			}
+			metric := family.GetMetric()[0]
+			if labelValue(metric, "metric_type") != "custom.googleapis.com/deleted" || metric.GetCounter().GetValue() != 1 {
+				t.Errorf("Expected the deleted metric type to be counted once, got %v", metric)
+			}
+		})
+	}
+}
+
+func TestStartupValidatePrefixes(t *testing.T) {
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE")},
+	}
+	service := newFakeMonitoringService(t, api)
+
+	tests := []struct {
+		name     string
+		prefixes []string
+		validate bool
+		valid    bool
+	}{
+		{name: "matching prefixes", prefixes: []string{"custom.googleapis.com"}, validate: true, valid: true},
+		{name: "prefix matching nothing", prefixes: []string{"custom.googleapis.com", "custom.googlapis.com"}, validate: true},
+		{name: "validation disabled", prefixes: []string{"custom.googlapis.com"}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMonitoringCollector("test-project", service, MonitoringCollectorOptions{
+				MetricTypePrefixes:      tt.prefixes,
+				RequestInterval:         5 * time.Minute,
+				StartupValidatePrefixes: tt.validate,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if tt.valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.valid {
+				if err == nil {
+					t.Fatal("Expected an error for a prefix matching no metric descriptor")
+				}
+				if !strings.Contains(err.Error(), "custom.googlapis.com") || strings.Contains(err.Error(), "custom.googleapis.com") {
+					t.Errorf("Expected the error to only report the prefix matching nothing, got %v", err)
+				}
+			}
+		})
+	}
+
+	api.descriptorsStatus = http.StatusForbidden
+	if _, err := NewMonitoringCollector("test-project", service, MonitoringCollectorOptions{
+		MetricTypePrefixes:      []string{"custom.googleapis.com"},
+		RequestInterval:         5 * time.Minute,
+		StartupValidatePrefixes: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore()); err == nil {
+		t.Error("Expected an error when the metric descriptors cannot be listed")
+	}
+}
+
+func TestScrapeSummaryLog(t *testing.T) {
+	now := time.Now()
+	twoPoints := newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now.Add(-time.Minute))
+	twoPoints.Metric.Labels = map[string]string{"instance": "1"}
+	value := float64(2)
+	twoPoints.Points = append(twoPoints.Points, &monitoring.Point{
+		Interval: &monitoring.TimeInterval{EndTime: now.Format(time.RFC3339Nano)},
+		Value:    &monitoring.TypedValue{DoubleValue: &value},
+	})
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			api := &fakeMonitoringAPI{
+				descriptors: []*monitoring.MetricDescriptor{
+					newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+					newTestDescriptor("custom.googleapis.com/b", "GAUGE", "DOUBLE"),
+					newTestDescriptor("custom.googleapis.com/broken", "GAUGE", "DOUBLE"),
+				},
+				timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+					"custom.googleapis.com/a": {{TimeSeries: []*monitoring.TimeSeries{
+						twoPoints,
+						newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now),
+					}}},
+					"custom.googleapis.com/b": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/b", "GAUGE", 1, now)}}},
+				},
+				timeSeriesStatus: map[string]int{"custom.googleapis.com/broken": http.StatusInternalServerError},
+			}
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				ScrapeSummaryLog:   enabled,
+			}, logger, newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			gatherFamilies(t, collector)
+
+			var summary map[string]interface{}
+			for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+				var entry map[string]interface{}
+				if err := json.Unmarshal(line, &entry); err != nil {
+					t.Fatalf("Failed to parse log line %q: %v", line, err)
+				}
+				if entry["msg"] == "Scrape summary" {
+					summary = entry
+				}
+			}
+
+			if !enabled {
+				if summary != nil {
+					t.Errorf("Expected no scrape summary, got %v", summary)
+				}
+				return
+			}
+			if summary == nil {
+				t.Fatal("Expected a scrape summary to be logged")
+			}
+			expected := map[string]float64{
+				"prefixes":    1,
+				"descriptors": 3,
+				"series":      3,
+				"samples":     4,
+				"api_calls":   4,
+				"errors":      1,
+			}
+			for key, want := range expected {
+				if got, ok := summary[key].(float64); !ok || got != want {
+					t.Errorf("Expected %s to be %v, got %v", key, want, summary[key])
+				}
+			}
+			if duration, ok := summary["duration"].(float64); !ok || duration <= 0 {
+				t.Errorf("Expected a positive duration, got %v", summary["duration"])
+			}
+		})
+	}
+}
+
+func TestPostScrapeHook(t *testing.T) {
+	now := time.Now()
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/b", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/broken", "GAUGE", "DOUBLE"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/a": {{TimeSeries: []*monitoring.TimeSeries{
+				newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now),
+				newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 2, now),
+			}}},
+			"custom.googleapis.com/b": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/b", "GAUGE", 1, now)}}},
+		},
+		timeSeriesStatus: map[string]int{"custom.googleapis.com/broken": http.StatusInternalServerError},
+	}
+	api.timeSeries["custom.googleapis.com/a"][0].TimeSeries[1].Metric.Labels = map[string]string{"instance": "1"}
+
+	var summaries []ScrapeSummary
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		PostScrapeHook: func(summary ScrapeSummary) {
+			summaries = append(summaries, summary)
+		},
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+	if len(summaries) != 1 {
+		t.Fatalf("Expected the hook to be called once, got %d", len(summaries))
+	}
+	summary := summaries[0]
+
+	expected := map[string]int{
+		"stackdriver_gce_instance_custom_googleapis_com_a": 2,
+		"stackdriver_gce_instance_custom_googleapis_com_b": 1,
+	}
+	if !reflect.DeepEqual(summary.Families, expected) {
+		t.Errorf("Expected families %v, got %v", expected, summary.Families)
+	}
+	for name, count := range expected {
+		if got := len(families[name].GetMetric()); got != count {
+			t.Errorf("Expected %d gathered metrics for %s, got %d", count, name, got)
+		}
+	}
+	if summary.ProjectID != "test-project" || summary.Metrics != 3 || summary.Descriptors != 3 || summary.Series != 3 ||
+		summary.Samples != 3 || summary.Errors != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if summary.Err == nil {
+		t.Error("Expected the error of the broken metric type to fail the scrape")
+	}
+	if summary.Duration <= 0 {
+		t.Errorf("Expected a positive duration, got %v", summary.Duration)
+	}
+}
+
+func TestFamiliesCountedWithPostScrapeHook(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())},
+	}
+
+	for _, hook := range []func(ScrapeSummary){nil, func(ScrapeSummary) {}} {
+		collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+			RequestInterval: 5 * time.Minute,
+			PostScrapeHook:  hook,
+		}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+		if err != nil {
+			t.Fatalf("Failed to create collector: %v", err)
+		}
+
+		ch := make(chan prometheus.Metric, 1)
+		state := newScrapeState(nil)
+		if err := collector.reportTimeSeriesMetrics(page, descriptor, ch, time.Now(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := map[string]int{}
+		if hook != nil {
+			expected["stackdriver_gce_instance_custom_googleapis_com_requests"] = 1
+		}
+		if got := state.familyCounts(); !maps.Equal(got, expected) {
+			t.Errorf("Expected the families %v with a hook %v, got %v", expected, hook != nil, got)
+		}
+	}
+}
+
+// testCounterStore is a simplified DeltaCounterStore which sums all the increments of a series.
+type testCounterStore struct {
+	mu      sync.Mutex
+	metrics map[string]map[string]*ConstMetric
+}
+
+func newTestCounterStore() *testCounterStore {
+	return &testCounterStore{metrics: make(map[string]map[string]*ConstMetric)}
+}
+
+func (s *testCounterStore) Increment(metricDescriptor *monitoring.MetricDescriptor, currentValue *ConstMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.metrics[metricDescriptor.Name]; !ok {
+		s.metrics[metricDescriptor.Name] = make(map[string]*ConstMetric)
+	}
+	key := currentValue.FqName + "|" + strings.Join(currentValue.LabelValues, "|")
+	currentValue.CreatedTime = currentValue.CollectionTime
+	if existing, ok := s.metrics[metricDescriptor.Name][key]; ok {
+		currentValue.Value += existing.Value
+		currentValue.CreatedTime = existing.CreatedTime
+	}
+	s.metrics[metricDescriptor.Name][key] = currentValue
+}
+
+func (s *testCounterStore) ListMetrics(metricDescriptorName string) []*ConstMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var output []*ConstMetric
+	for _, metric := range s.metrics[metricDescriptorName] {
+		metricCopy := *metric
+		output = append(output, &metricCopy)
+	}
+	return output
+}
+
+// testHistogramStore is a simplified DeltaHistogramStore which merges all the increments of a series.
+type testHistogramStore struct {
+	mu      sync.Mutex
+	metrics map[string]map[string]*HistogramMetric
+}
+
+func newTestHistogramStore() *testHistogramStore {
+	return &testHistogramStore{metrics: make(map[string]map[string]*HistogramMetric)}
+}
+
+func (s *testHistogramStore) Increment(metricDescriptor *monitoring.MetricDescriptor, currentValue *HistogramMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.metrics[metricDescriptor.Name]; !ok {
+		s.metrics[metricDescriptor.Name] = make(map[string]*HistogramMetric)
+	}
+	key := currentValue.FqName + "|" + strings.Join(currentValue.LabelValues, "|")
+	if existing, ok := s.metrics[metricDescriptor.Name][key]; ok {
+		currentValue.MergeHistogram(existing)
+	}
+	s.metrics[metricDescriptor.Name][key] = currentValue
+}
+
+func (s *testHistogramStore) ListMetrics(metricDescriptorName string) []*HistogramMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var output []*HistogramMetric
+	for _, metric := range s.metrics[metricDescriptorName] {
+		metricCopy := *metric
+		output = append(output, &metricCopy)
+	}
+	return output
+}
+
+var fqNameRE = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// reportPage runs reportTimeSeriesMetrics for a single page and returns the reported metrics by name.
+func reportPage(t *testing.T, collector *MonitoringCollector, page *monitoring.ListTimeSeriesResponse, descriptor *monitoring.MetricDescriptor) map[string][]*dto.Metric {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collector.reportTimeSeriesMetrics(page, descriptor, ch, time.Now(), nil)
+		close(ch)
+	}()
+
+	metrics := make(map[string][]*dto.Metric)
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatalf("Failed to write metric: %v", err)
+		}
+		fqName := fqNameRE.FindStringSubmatch(metric.Desc().String())[1]
+		metrics[fqName] = append(metrics[fqName], m)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Unexpected error reporting time series: %v", err)
+	}
+	return metrics
+}
+
+func newTestDistributionTimeSeries(metricType, metricKind string, dist *monitoring.Distribution, endTime time.Time) *monitoring.TimeSeries {
+	return &monitoring.TimeSeries{
+		Metric:     &monitoring.Metric{Type: metricType, Labels: map[string]string{}},
+		Resource:   &monitoring.MonitoredResource{Type: "gce_instance", Labels: map[string]string{"project_id": "test-project"}},
+		MetricKind: metricKind,
+		ValueType:  "DISTRIBUTION",
+		Points: []*monitoring.Point{
+			{
+				Interval: &monitoring.TimeInterval{EndTime: endTime.Format(time.RFC3339Nano)},
+				Value:    &monitoring.TypedValue{DistributionValue: dist},
+			},
+		},
+	}
+}
+
+func TestEmptyExplicitBuckets(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DISTRIBUTION")
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{
+			newTestDistributionTimeSeries(metricType, "GAUGE", &monitoring.Distribution{
+				Count:         3,
+				Mean:          2,
+				BucketCounts:  googleapi.Int64s{3},
+				BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{}},
+			}, time.Now()),
+		},
+	}
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes:    []string{"custom.googleapis.com"},
+				RequestInterval:       5 * time.Minute,
+				StrictExplicitBuckets: strict,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			metrics := reportPage(t, collector, page, descriptor)["stackdriver_gce_instance_custom_googleapis_com_latency"]
+
+			expectedMetrics := 1
+			if strict {
+				expectedMetrics = 0
+			}
+			if len(metrics) != expectedMetrics {
+				t.Fatalf("Expected %d metrics, got %d", expectedMetrics, len(metrics))
+			}
+			if !strict {
+				buckets := metrics[0].GetHistogram().GetBucket()
+				if len(buckets) != 1 || !math.IsInf(buckets[0].GetUpperBound(), 1) || metrics[0].GetHistogram().GetSampleCount() != 3 {
+					t.Errorf("Expected a single +Inf bucket histogram with 3 samples, got %v", metrics[0].GetHistogram())
+				}
+			}
+
+			if got := testutil.ToFloat64(collector.emptyExplicitBucketsTotalMetric.WithLabelValues(metricType)); got != 1 {
+				t.Errorf("Expected empty explicit buckets counter to be 1, got %v", got)
+			}
+		})
+	}
+}
+
+func TestGaugeDistribution(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_latency"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DISTRIBUTION")
+
+	histogramStore := newTestHistogramStore()
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		AggregateDeltas:    true,
+		CreatedTimestamps:  true,
+	}, slog.Default(), newTestCounterStore(), histogramStore)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	// Every scrape reports the distribution as is, a GAUGE distribution is not accumulated like a DELTA one
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 2; i++ {
+		page := &monitoring.ListTimeSeriesResponse{
+			TimeSeries: []*monitoring.TimeSeries{
+				newTestDistributionTimeSeries(metricType, "GAUGE", &monitoring.Distribution{
+					Count:         4,
+					Mean:          2,
+					BucketCounts:  googleapi.Int64s{1, 3},
+					BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{Bounds: []float64{1}}},
+				}, start.Add(time.Duration(i)*time.Minute)),
+			},
+		}
+
+		metrics := reportPage(t, collector, page, descriptor)[fqName]
+		if len(metrics) != 1 {
+			t.Fatalf("Scrape %d: expected 1 %s metric, got %d", i, fqName, len(metrics))
+		}
+		histogram := metrics[0].GetHistogram()
+		if histogram == nil {
+			t.Fatalf("Scrape %d: expected a histogram, got %v", i, metrics[0])
+		}
+		if histogram.GetSampleCount() != 4 || histogram.GetSampleSum() != 8 {
+			t.Errorf("Scrape %d: expected 4 samples summing to 8, got %d summing to %v", i, histogram.GetSampleCount(), histogram.GetSampleSum())
+		}
+		if histogram.GetCreatedTimestamp() != nil {
+			t.Errorf("Scrape %d: expected no created timestamp, got %v", i, histogram.GetCreatedTimestamp().AsTime())
+		}
+	}
+
+	if stored := histogramStore.ListMetrics(descriptor.Name); len(stored) != 0 {
+		t.Errorf("Expected no histogram in the delta store, got %d", len(stored))
+	}
+}
+
+func TestLastSeenMetrics(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	newest := time.Now().Truncate(time.Millisecond)
+
+	timeSeries := newTestTimeSeries(metricType, "GAUGE", 1, newest.Add(-time.Minute))
+	value := float64(2)
+	timeSeries.Points = append(timeSeries.Points, &monitoring.Point{
+		Interval: &monitoring.TimeInterval{EndTime: newest.Format(time.RFC3339Nano)},
+		Value:    &monitoring.TypedValue{DoubleValue: &value},
+	})
+	page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{timeSeries}}
+
+	for _, fillMissingLabels := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fillMissingLabels=%v", fillMissingLabels), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				FillMissingLabels:  fillMissingLabels,
+				LastSeenMetrics:    true,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			metrics := reportPage(t, collector, page, descriptor)
+			if got := metrics["stackdriver_gce_instance_custom_googleapis_com_requests"]; len(got) != 1 || got[0].GetGauge().GetValue() != value {
+				t.Errorf("Expected the newest point value %v to be reported, got %v", value, got)
+			}
+
+			lastSeen := metrics["stackdriver_gce_instance_custom_googleapis_com_requests_last_seen_seconds"]
+			if len(lastSeen) != 1 {
+				t.Fatalf("Expected 1 last seen metric, got %d", len(lastSeen))
+			}
+			if got := lastSeen[0].GetGauge().GetValue(); got != float64(newest.UnixNano())/1e9 {
+				t.Errorf("Expected last seen to be %v, got %v", float64(newest.UnixNano())/1e9, got)
+			}
+			if lastSeen[0].GetTimestampMs() != newest.UnixMilli() {
+				t.Errorf("Expected last seen timestamp to be %v, got %v", newest.UnixMilli(), lastSeen[0].GetTimestampMs())
+			}
+		})
+	}
+}
+
+func TestLastSeenMetricsDiscardedSeries(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	lastSeenName := "stackdriver_gce_instance_custom_googleapis_com_requests_last_seen_seconds"
+
+	emptyBuckets := newTestDistributionTimeSeries(metricType, "GAUGE", &monitoring.Distribution{
+		Count:         1,
+		BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{}},
+		BucketCounts:  googleapi.Int64s{1},
+	}, time.Now())
+	unsupported := newTestTimeSeries(metricType, "GAUGE", 1, time.Now())
+	unsupported.ValueType = "STRING"
+
+	tests := []struct {
+		name       string
+		opts       MonitoringCollectorOptions
+		valueType  string
+		timeSeries *monitoring.TimeSeries
+	}{
+		{
+			name:       "metric kind not in the policy",
+			opts:       MonitoringCollectorOptions{MetricTypePolicy: MetricTypePolicy{{MetricKind: "CUMULATIVE"}: prometheus.CounterValue}},
+			valueType:  "DOUBLE",
+			timeSeries: newTestTimeSeries(metricType, "GAUGE", 1, time.Now()),
+		},
+		{
+			name:       "strict explicit buckets",
+			opts:       MonitoringCollectorOptions{StrictExplicitBuckets: true},
+			valueType:  "DISTRIBUTION",
+			timeSeries: emptyBuckets,
+		},
+		{
+			name:       "unsupported value type",
+			valueType:  "STRING",
+			timeSeries: unsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.MetricTypePrefixes = []string{"custom.googleapis.com"}
+			opts.RequestInterval = 5 * time.Minute
+			opts.LastSeenMetrics = true
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, opts, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{tt.timeSeries}}
+			metrics := reportPage(t, collector, page, newTestDescriptor(metricType, "GAUGE", tt.valueType))
+			if got := metrics[lastSeenName]; len(got) != 0 {
+				t.Errorf("Expected no last seen metric for a discarded series, got %v", got)
+			}
+		})
+	}
+}
+
+func TestAPICallsLastScrape(t *testing.T) {
+	now := time.Now()
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/b", "GAUGE", "DOUBLE"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/a": {
+				{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now)}},
+				{TimeSeries: []*monitoring.TimeSeries{}},
+			},
+			"custom.googleapis.com/b": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/b", "GAUGE", 1, now)}}},
+		},
+	}
+
+	// A descriptors listing outside of the scrape is made while the time series of b are requested
+	var collector *MonitoringCollector
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m := fakeMetricTypeRE.FindStringSubmatch(r.URL.Query().Get("filter")); m != nil && m[1] == "custom.googleapis.com/b" {
+			if err := collector.Validate(); err != nil {
+				t.Errorf("Failed to validate the prefixes: %v", err)
+			}
+		}
+		api.ServeHTTP(w, r)
+	})
+
+	var err error
+	collector, err = NewMonitoringCollector("test-project", newFakeMonitoringService(t, handler), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		APICallsLastScrape: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	// 1 descriptors page, 2 time series pages for a and 1 time series page for b
+	expectedCalls := float64(4)
+	for scrape := 1; scrape <= 2; scrape++ {
+		families := gatherFamilies(t, collector)
+
+		if got := families["stackdriver_monitoring_api_calls_last_scrape"].GetMetric()[0].GetGauge().GetValue(); got != expectedCalls {
+			t.Errorf("Scrape %d: expected %v API calls in the last scrape, got %v", scrape, expectedCalls, got)
+		}
+		if got := families["stackdriver_monitoring_api_calls_total"].GetMetric()[0].GetCounter().GetValue(); got != (expectedCalls+1)*float64(scrape) {
+			t.Errorf("Scrape %d: expected %v API calls in total, got %v", scrape, (expectedCalls+1)*float64(scrape), got)
+		}
+	}
+}
+
+func TestFuturePoints(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	future := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, future)},
+	}
+
+	tests := []struct {
+		policy          string
+		expectedMetrics int
+	}{
+		{policy: "", expectedMetrics: 1},
+		{policy: FuturePointsKeep, expectedMetrics: 1},
+		{policy: FuturePointsDrop, expectedMetrics: 0},
+		{policy: FuturePointsClamp, expectedMetrics: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("policy=%q", tt.policy), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				FuturePoints:       tt.policy,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			begun := time.Now()
+			metrics := reportPage(t, collector, page, descriptor)[fqName]
+			if len(metrics) != tt.expectedMetrics {
+				t.Fatalf("Expected %d metrics, got %d", tt.expectedMetrics, len(metrics))
+			}
+
+			if tt.expectedMetrics > 0 {
+				timestamp := time.UnixMilli(metrics[0].GetTimestampMs())
+				if tt.policy == FuturePointsClamp {
+					if timestamp.After(time.Now()) || timestamp.Before(begun.Truncate(time.Millisecond)) {
+						t.Errorf("Expected timestamp to be clamped to the scrape time, got %v", timestamp)
+					}
+				} else if !timestamp.Equal(future) {
+					t.Errorf("Expected timestamp %v to be kept, got %v", future, timestamp)
+				}
+			}
+
+			if got := testutil.ToFloat64(collector.futurePointsTotalMetric.WithLabelValues(metricType)); got != 1 {
+				t.Errorf("Expected future points counter to be 1, got %v", got)
+			}
+		})
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval: 5 * time.Minute,
+		FuturePoints:    "ignore",
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for an unknown future points policy")
+	}
+}
+
+func TestRequestIntervalValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		offset   time.Duration
+		valid    bool
+	}{
+		{name: "positive interval", interval: 5 * time.Minute, valid: true},
+		{name: "positive interval and offset", interval: 5 * time.Minute, offset: time.Minute, valid: true},
+		{name: "zero interval", interval: 0},
+		{name: "negative interval", interval: -time.Minute},
+		{name: "negative offset", interval: 5 * time.Minute, offset: -time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				RequestInterval: tt.interval,
+				RequestOffset:   tt.offset,
+			}, slog.Default(), nil, nil)
+			if tt.valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected an error for an invalid request interval or offset")
+			}
+		})
+	}
+}
+
+func TestNewestPointTies(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	endTime := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	older, first, last := 1.0, 2.0, 3.0
+	newTiedTimeSeries := func(metricKind string) *monitoring.TimeSeries {
+		timeSeries := newTestTimeSeries(metricType, metricKind, first, endTime)
+		timeSeries.Points = append(timeSeries.Points,
+			&monitoring.Point{
+				Interval: &monitoring.TimeInterval{EndTime: endTime.Add(-time.Minute).Format(time.RFC3339Nano)},
+				Value:    &monitoring.TypedValue{DoubleValue: &older},
+			},
+			&monitoring.Point{
+				Interval: &monitoring.TimeInterval{EndTime: endTime.Format(time.RFC3339Nano)},
+				Value:    &monitoring.TypedValue{DoubleValue: &last},
+			},
+		)
+		return timeSeries
+	}
+
+	tests := []struct {
+		policy     string
+		metricKind string
+		expected   float64
+	}{
+		{policy: "", metricKind: "DELTA", expected: first},
+		{policy: NewestPointFirst, metricKind: "DELTA", expected: first},
+		{policy: NewestPointLast, metricKind: "DELTA", expected: last},
+		{policy: NewestPointSum, metricKind: "DELTA", expected: first + last},
+		{policy: NewestPointSum, metricKind: "GAUGE", expected: first},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("policy=%q,kind=%s", tt.policy, tt.metricKind), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				NewestPointTies:    tt.policy,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			page := &monitoring.ListTimeSeriesResponse{
+				TimeSeries: []*monitoring.TimeSeries{newTiedTimeSeries(tt.metricKind)},
+			}
+			metrics := reportPage(t, collector, page, newTestDescriptor(metricType, tt.metricKind, "DOUBLE"))[fqName]
+			if len(metrics) != 1 {
+				t.Fatalf("Expected 1 metric, got %d", len(metrics))
+			}
+			if got := metrics[0].GetGauge().GetValue(); got != tt.expected {
+				t.Errorf("Expected value %v, got %v", tt.expected, got)
+			}
+			if got := time.UnixMilli(metrics[0].GetTimestampMs()); !got.Equal(endTime) {
+				t.Errorf("Expected timestamp %v, got %v", endTime, got)
+			}
+		})
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval: 5 * time.Minute,
+		NewestPointTies: "max",
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for an unknown newest point ties policy")
+	}
+}
+
+func TestUnitConflicts(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_latency"
+	newDescriptor := func(projectID, unit string) *monitoring.MetricDescriptor {
+		descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+		descriptor.Name = "projects/" + projectID + "/metricDescriptors/" + metricType
+		descriptor.Unit = unit
+		return descriptor
+	}
+
+	tests := []struct {
+		policy   string
+		units    []string
+		expected string
+	}{
+		{policy: "", units: []string{"By", "s"}, expected: "s"},
+		{policy: UnitConflictsWarn, units: []string{"By", "s"}, expected: "s"},
+		{policy: UnitConflictsWarn, units: []string{"s", "By"}, expected: "By"},
+		{policy: UnitConflictsPick, units: []string{"By", "s"}, expected: "By"},
+		{policy: UnitConflictsPick, units: []string{"s", "By"}, expected: "By"},
+		{policy: UnitConflictsDrop, units: []string{"By", "s"}, expected: ""},
+		{policy: UnitConflictsDrop, units: []string{"s", "s"}, expected: "s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("policy=%q,units=%v", tt.policy, tt.units), func(t *testing.T) {
+			descriptors := []*monitoring.MetricDescriptor{
+				newDescriptor("test-project", tt.units[0]),
+				newDescriptor("delegated-project", tt.units[1]),
+			}
+			api := &fakeMonitoringAPI{
+				descriptors: descriptors,
+				timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+					metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+				},
+			}
+
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				UnitConflicts:      tt.policy,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			metrics := gatherFamilies(t, collector)[fqName].GetMetric()
+			if len(metrics) != 1 {
+				t.Fatalf("Expected 1 metric, got %d", len(metrics))
+			}
+			if got := labelValue(metrics[0], "unit"); got != tt.expected {
+				t.Errorf("Expected unit %q, got %q", tt.expected, got)
+			}
+			for i, descriptor := range descriptors {
+				if descriptor.Unit != tt.units[i] {
+					t.Errorf("Expected descriptor %d to keep unit %q, got %q", i, tt.units[i], descriptor.Unit)
+				}
+			}
+		})
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval: 5 * time.Minute,
+		UnitConflicts:   "first",
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for an unknown unit conflicts policy")
+	}
+}
+
+func TestUnitConflictsWarnOnce(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	var descriptors []*monitoring.MetricDescriptor
+	for projectID, unit := range map[string]string{"test-project": "By", "delegated-project": "s"} {
+		descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+		descriptor.Name = "projects/" + projectID + "/metricDescriptors/" + metricType
+		descriptor.Unit = unit
+		descriptors = append(descriptors, descriptor)
+	}
+	api := &fakeMonitoringAPI{
+		descriptors: descriptors,
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+		},
+	}
+
+	var logs bytes.Buffer
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+	}, slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		gatherFamilies(t, collector)
+	}
+
+	if got := strings.Count(logs.String(), "level=WARN msg=\"metric descriptors of the same type report different units\""); got != 1 {
+		t.Errorf("Expected the unit conflict to be logged once as a warning, got %d", got)
+	}
+	if got := strings.Count(logs.String(), "level=DEBUG msg=\"metric descriptors of the same type report different units\""); got != 2 {
+		t.Errorf("Expected the repeated unit conflicts to be logged as debug, got %d", got)
+	}
+	if got := testutil.ToFloat64(collector.unitConflictsTotalMetric.WithLabelValues(metricType)); got != 3 {
+		t.Errorf("Expected 3 unit conflicts, got %v", got)
+	}
+}
+
+// concurrencyTracker records the maximum number of concurrent time series requests, in total and per metric type prefix.
+type concurrencyTracker struct {
+	api    http.Handler
+	prefix func(metricType string) string
+
+	mu          sync.Mutex
+	inFlight    map[string]int
+	maxInFlight map[string]int
+}
+
+func (c *concurrencyTracker) track(key string, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight[key] += delta
+	c.maxInFlight[key] = max(c.maxInFlight[key], c.inFlight[key])
+}
+
+func (c *concurrencyTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m := fakeMetricTypeRE.FindStringSubmatch(r.URL.Query().Get("filter")); m != nil {
+		prefix := c.prefix(m[1])
+		c.track("", 1)
+		c.track(prefix, 1)
+		defer c.track("", -1)
+		defer c.track(prefix, -1)
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.api.ServeHTTP(w, r)
+}
+
+func TestConcurrencyCaps(t *testing.T) {
+	api := &fakeMonitoringAPI{}
+	for _, prefix := range []string{"custom.googleapis.com/busy", "custom.googleapis.com/quiet"} {
+		for i := 0; i < 4; i++ {
+			api.descriptors = append(api.descriptors, newTestDescriptor(fmt.Sprintf("%s/metric_%d", prefix, i), "GAUGE", "DOUBLE"))
+		}
+	}
+	tracker := &concurrencyTracker{
+		api: api,
+		prefix: func(metricType string) string {
+			return metricType[:strings.LastIndex(metricType, "/")]
+		},
+		inFlight:    map[string]int{},
+		maxInFlight: map[string]int{},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, tracker), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com/busy", "custom.googleapis.com/quiet"},
+		RequestInterval:    5 * time.Minute,
+		MaxConcurrency:     3,
+		PrefixConcurrency:  map[string]int{"custom.googleapis.com/busy": 1},
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	gatherFamilies(t, collector)
+
+	if got := len(api.timeSeriesRequests); got != 8 {
+		t.Fatalf("Expected 8 time series requests, got %d", got)
+	}
+	if got := tracker.maxInFlight[""]; got > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", got)
+	}
+	if got := tracker.maxInFlight["custom.googleapis.com/busy"]; got != 1 {
+		t.Errorf("Expected at most 1 concurrent request for the capped prefix, got %d", got)
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:   5 * time.Minute,
+		PrefixConcurrency: map[string]int{"custom.googleapis.com": -1},
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for a negative prefix concurrency")
+	}
+}
+
+func TestConcurrencyCapBoundsDescriptors(t *testing.T) {
+	api := &fakeMonitoringAPI{}
+	for i := 0; i < 50; i++ {
+		api.descriptors = append(api.descriptors, newTestDescriptor(fmt.Sprintf("custom.googleapis.com/metric_%d", i), "GAUGE", "DOUBLE"))
+	}
+	// The first time series request holds the only slot of the collector semaphore until it is released
+	blocked, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/timeSeries") {
+			once.Do(func() {
+				close(blocked)
+				<-release
+			})
+		}
+		api.ServeHTTP(w, r)
+	})
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, handler), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		MaxConcurrency:     1,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		for range ch {
+		}
+	}()
+	state := newScrapeState(nil)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collector.reportMonitoringMetrics(ch, time.Now(), state)
+		close(ch)
+	}()
+
+	// The next descriptor waits for the slot before its goroutine is started, the following ones are not reached
+	<-blocked
+	if descriptors, _, _, _ := state.counts(); descriptors > 2 {
+		t.Errorf("Expected at most 2 descriptors to be dispatched while the semaphore is full, got %d", descriptors)
+	}
+	close(release)
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := len(api.timeSeriesRequests); got != 50 {
+		t.Errorf("Expected 50 time series requests, got %d", got)
+	}
+}
+
+func TestSkipInvalidPoints(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	endTime := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	invalid := newTestTimeSeries(metricType, "GAUGE", 2, endTime)
+	invalid.Metric.Labels["instance"] = "invalid"
+	invalid.Points[0].Interval.EndTime = "yesterday"
+	mixed := newTestTimeSeries(metricType, "GAUGE", 3, endTime)
+	mixed.Metric.Labels["instance"] = "mixed"
+	mixed.Points = append(mixed.Points, &monitoring.Point{
+		Interval: &monitoring.TimeInterval{EndTime: "not a time"},
+		Value:    invalid.Points[0].Value,
+	})
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, endTime), invalid, mixed},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	if err := collector.reportTimeSeriesMetrics(page, descriptor, make(chan prometheus.Metric, 10), time.Now(), nil); err == nil {
+		t.Error("Expected an error for an invalid point end time without skipping invalid points")
+	}
+
+	collector, err = NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		SkipInvalidPoints:  true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	metrics := reportPage(t, collector, page, descriptor)[fqName]
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 metrics, got %d", len(metrics))
+	}
+	if got := testutil.ToFloat64(collector.invalidPointsTotalMetric.WithLabelValues(metricType)); got != 2 {
+		t.Errorf("Expected invalid points counter to be 2, got %v", got)
+	}
+}
+
+func TestDelegatedSeriesDropped(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+	now := time.Now()
+	delegated := newTestTimeSeries(metricType, "GAUGE", 1, now)
+	delegated.Resource.Labels["project_id"] = "delegated-project"
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{
+			newTestTimeSeries(metricType, "GAUGE", 1, now),
+			delegated,
+			delegated,
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes:    []string{"custom.googleapis.com"},
+		RequestInterval:       5 * time.Minute,
+		DropDelegatedProjects: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	if metrics := reportPage(t, collector, page, descriptor)[fqName]; len(metrics) != 1 {
+		t.Fatalf("Expected 1 metric, got %d", len(metrics))
+	}
+	if got := testutil.ToFloat64(collector.delegatedSeriesDroppedMetric.WithLabelValues(metricType)); got != 2 {
+		t.Errorf("Expected delegated series dropped counter to be 2, got %v", got)
+	}
+
+	collector, err = NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	if collector.delegatedSeriesDroppedMetric != nil {
+		t.Error("Expected no delegated series dropped counter without dropping delegated projects")
+	}
+}
+
+func TestTimeSeriesFilter(t *testing.T) {
+	descriptor := newTestDescriptor("compute.googleapis.com/instance/cpu/utilization", "GAUGE", "DOUBLE")
+
+	tests := []struct {
+		name     string
+		opts     MonitoringCollectorOptions
+		expected string
+	}{
+		{
+			name:     "metric type only",
+			opts:     MonitoringCollectorOptions{},
+			expected: `metric.type="compute.googleapis.com/instance/cpu/utilization"`,
+		},
+		{
+			name: "resource label filters",
+			opts: MonitoringCollectorOptions{
+				ResourceLabelFilters: map[string]string{"zone": "us-east1-b", "env": "prod"},
+			},
+			expected: `metric.type="compute.googleapis.com/instance/cpu/utilization" AND resource.labels.env = "prod" AND resource.labels.zone = "us-east1-b"`,
+		},
+		{
+			name: "resource label filters with extra filters and delegated projects",
+			opts: MonitoringCollectorOptions{
+				ExtraFilters:          []MetricFilter{{TargetedMetricPrefix: "compute.googleapis.com", FilterQuery: `metric.labels.state="used"`}},
+				ResourceLabelFilters:  map[string]string{"env": `pr"od`},
+				DropDelegatedProjects: true,
+			},
+			expected: `project="test-project" AND metric.type="compute.googleapis.com/instance/cpu/utilization" AND (metric.labels.state="used") AND resource.labels.env = "pr\"od"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.RequestInterval = 5 * time.Minute
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, tt.opts, slog.Default(), nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			if got := collector.timeSeriesFilter(descriptor, &ScrapeConfig{}); got != tt.expected {
+				t.Errorf("Expected filter:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDistributionFallback(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_latency"
+	descriptor := newTestDescriptor(metricType, "CUMULATIVE", "DISTRIBUTION")
+	page := &monitoring.ListTimeSeriesResponse{
+		TimeSeries: []*monitoring.TimeSeries{
+			newTestDistributionTimeSeries(metricType, "CUMULATIVE", &monitoring.Distribution{
+				Count:         4,
+				Mean:          2.5,
+				BucketCounts:  googleapi.Int64s{4},
+				BucketOptions: &monitoring.BucketOptions{},
+			}, time.Now()),
+		},
+	}
+
+	for _, fallback := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fallback=%v", fallback), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes:   []string{"custom.googleapis.com"},
+				RequestInterval:      5 * time.Minute,
+				FillMissingLabels:    true,
+				DistributionFallback: fallback,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			metrics := reportPage(t, collector, page, descriptor)
+			if len(metrics[fqName]) != 0 {
+				t.Errorf("Expected no histogram to be reported, got %v", metrics[fqName])
+			}
+
+			if fallback {
+				count := metrics[fqName+"_count"]
+				if len(count) != 1 || count[0].GetCounter().GetValue() != 4 {
+					t.Errorf("Expected a _count counter of 4, got %v", count)
+				}
+				sum := metrics[fqName+"_sum"]
+				if len(sum) != 1 || sum[0].GetCounter().GetValue() != 10 {
+					t.Errorf("Expected a _sum counter of 10, got %v", sum)
+				}
+			} else if len(metrics) != 0 {
+				t.Errorf("Expected no metrics to be reported, got %v", metrics)
+			}
+
+			if got := testutil.ToFloat64(collector.histogramErrorsTotalMetric.WithLabelValues(metricType)); got != 1 {
+				t.Errorf("Expected histogram errors counter to be 1, got %v", got)
+			}
+		})
+	}
+}
+
+func TestExplicitTargets(t *testing.T) {
+	now := time.Now()
+	api := &fakeMonitoringAPI{
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/a": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now)}}},
+			"custom.googleapis.com/b": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/b", "GAUGE", 2, now)}}},
+			"custom.googleapis.com/c": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/c", "GAUGE", 3, now)}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		ExplicitTargets: []MetricTarget{
+			{
+				ProjectID:  "test-project",
+				MetricType: "custom.googleapis.com/a",
+				Aggregation: &MetricAggregationConfig{
+					AlignmentPeriod:  "60s",
+					PerSeriesAligner: "ALIGN_MEAN",
+				},
+			},
+			{MetricType: "custom.googleapis.com/b", MetricKind: "GAUGE", ValueType: "DOUBLE"},
+			{ProjectID: "other-project", MetricType: "custom.googleapis.com/c"},
+		},
+		RequestInterval: 5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+
+	if api.descriptorRequests != 0 {
+		t.Errorf("Expected no metric descriptors requests, got %d", api.descriptorRequests)
+	}
+	if got := families["stackdriver_monitoring_last_scrape_error"].GetMetric()[0].GetGauge().GetValue(); got != 0 {
+		t.Errorf("Expected no scrape error, got %v", got)
+	}
+
+	expected := map[string]float64{
+		"stackdriver_gce_instance_custom_googleapis_com_a": 1,
+		"stackdriver_gce_instance_custom_googleapis_com_b": 2,
+	}
+	for fqName, value := range expected {
+		family, ok := families[fqName]
+		if !ok {
+			t.Errorf("Expected metric %s to be reported", fqName)
+			continue
+		}
+		if got := family.GetMetric()[0].GetGauge().GetValue(); got != value {
+			t.Errorf("Expected %s to be %v, got %v", fqName, value, got)
+		}
+	}
+	if _, ok := families["stackdriver_gce_instance_custom_googleapis_com_c"]; ok {
+		t.Error("Expected the target of another project not to be reported")
+	}
+
+	for _, request := range api.timeSeriesRequests {
+		alignmentPeriod := request.Get("aggregation.alignmentPeriod")
+		switch {
+		case strings.Contains(request.Get("filter"), "custom.googleapis.com/a"):
+			if alignmentPeriod != "60s" {
+				t.Errorf("Expected the target aggregation to be used, got alignment period %q", alignmentPeriod)
+			}
+		case alignmentPeriod != "":
+			t.Errorf("Expected no aggregation for %s, got alignment period %q", request.Get("filter"), alignmentPeriod)
+		}
+	}
+}
+
+func TestExplicitTargetsCoveredByPrefix(t *testing.T) {
+	now := time.Now()
+	kept := newTestDescriptor("custom.googleapis.com/kept", "GAUGE", "DOUBLE")
+	kept.LaunchStage = "GA"
+	discarded := newTestDescriptor("custom.googleapis.com/discarded", "GAUGE", "DOUBLE")
+	discarded.LaunchStage = "ALPHA"
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{kept, discarded},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/kept":      {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/kept", "GAUGE", 1, now)}}},
+			"custom.googleapis.com/discarded": {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/discarded", "GAUGE", 2, now)}}},
+			"custom.googleapis.com/unlisted":  {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/unlisted", "GAUGE", 3, now)}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:  []string{"custom.googleapis.com"},
+		DescriptorPredicate: DescriptorPredicate{LaunchStages: []string{"GA"}},
+		ExplicitTargets: []MetricTarget{
+			{MetricType: "custom.googleapis.com/kept"},
+			{MetricType: "custom.googleapis.com/discarded"},
+			{MetricType: "custom.googleapis.com/unlisted"},
+		},
+		RequestInterval: 5 * time.Minute,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+
+	for fqName, value := range map[string]float64{
+		"stackdriver_gce_instance_custom_googleapis_com_kept":      1,
+		"stackdriver_gce_instance_custom_googleapis_com_discarded": 2,
+		"stackdriver_gce_instance_custom_googleapis_com_unlisted":  3,
+	} {
+		metrics := families[fqName].GetMetric()
+		if len(metrics) != 1 || metrics[0].GetGauge().GetValue() != value {
+			t.Errorf("Expected %s to be reported once with value %v, got %v", fqName, value, metrics)
+		}
+	}
+
+	requests := make(map[string]int)
+	for _, request := range api.timeSeriesRequests {
+		requests[fakeMetricTypeRE.FindStringSubmatch(request.Get("filter"))[1]]++
+	}
+	expected := map[string]int{
+		"custom.googleapis.com/kept":      1,
+		"custom.googleapis.com/discarded": 1,
+		"custom.googleapis.com/unlisted":  1,
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected time series requests %v, got %v", expected, requests)
+	}
+}
+
+func TestGaugeCounter(t *testing.T) {
+	metricType := "custom.googleapis.com/processed"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_processed"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:      5 * time.Minute,
+		GaugeCounterPrefixes: []string{"custom.googleapis.com"},
+		GaugeCounterTTL:      time.Hour,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	steps := []struct {
+		value    float64
+		endTime  time.Time
+		expected float64
+	}{
+		{value: 5, endTime: start, expected: 5},
+		{value: 8, endTime: start.Add(time.Minute), expected: 8},
+		// The same point reported again is not counted twice
+		{value: 8, endTime: start.Add(time.Minute), expected: 8},
+		// A decrease is a reset, the value is counted from zero
+		{value: 3, endTime: start.Add(2 * time.Minute), expected: 11},
+		{value: 4, endTime: start.Add(3 * time.Minute), expected: 12},
+	}
+
+	for i, step := range steps {
+		page := &monitoring.ListTimeSeriesResponse{
+			TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", step.value, step.endTime)},
+		}
+		metrics := reportPage(t, collector, page, descriptor)
+
+		if len(metrics[fqName]) != 1 {
+			t.Fatalf("Step %d: expected 1 %s metric, got %d", i, fqName, len(metrics[fqName]))
+		}
+		counter := metrics[fqName][0].GetCounter()
+		if counter == nil {
+			t.Fatalf("Step %d: expected %s to be reported as a counter", i, fqName)
+		}
+		if got := counter.GetValue(); got != step.expected {
+			t.Errorf("Step %d: expected counter value %v, got %v", i, step.expected, got)
+		}
+	}
+}
+
+func TestGaugeCounterDefaultTTL(t *testing.T) {
+	metricType := "custom.googleapis.com/processed"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_processed"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DOUBLE")
+
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:      5 * time.Minute,
+		GaugeCounterPrefixes: []string{"custom.googleapis.com"},
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	if collector.gaugeCounters.ttl != DefaultGaugeCounterTTL {
+		t.Errorf("Expected the default TTL %s, got %s", DefaultGaugeCounterTTL, collector.gaugeCounters.ttl)
+	}
+
+	// The previous values are kept across scrapes, so only the increments are counted
+	start := time.Now().Add(-time.Hour)
+	for i, expected := range []float64{5, 5, 5} {
+		collector.gaugeCounters.expire()
+		page := &monitoring.ListTimeSeriesResponse{
+			TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 5, start.Add(time.Duration(i)*time.Minute))},
+		}
+		metrics := reportPage(t, collector, page, descriptor)
+		if len(metrics[fqName]) != 1 {
+			t.Fatalf("Step %d: expected 1 %s metric, got %d", i, fqName, len(metrics[fqName]))
+		}
+		if got := metrics[fqName][0].GetCounter().GetValue(); got != expected {
+			t.Errorf("Step %d: expected counter value %v, got %v", i, expected, got)
+		}
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:      5 * time.Minute,
+		GaugeCounterPrefixes: []string{"custom.googleapis.com"},
+		GaugeCounterTTL:      -time.Minute,
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for a negative gauge counter TTL")
+	}
+}
+
+func TestMetricTypesScraped(t *testing.T) {
+	now := time.Now()
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/b", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/empty", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/future", "GAUGE", "DOUBLE"),
+		},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			"custom.googleapis.com/a": {{TimeSeries: []*monitoring.TimeSeries{
+				newTestTimeSeries("custom.googleapis.com/a", "GAUGE", 1, now),
+			}}},
+			"custom.googleapis.com/b": {
+				{TimeSeries: []*monitoring.TimeSeries{}},
+				{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries("custom.googleapis.com/b", "GAUGE", 1, now)}},
+			},
+			"custom.googleapis.com/empty": {{TimeSeries: []*monitoring.TimeSeries{}}},
+			// All the series of this metric type are dropped
+			"custom.googleapis.com/future": {{TimeSeries: []*monitoring.TimeSeries{
+				newTestTimeSeries("custom.googleapis.com/future", "GAUGE", 1, now.Add(time.Hour)),
+			}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		FuturePoints:       FuturePointsDrop,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+
+	if got := families["stackdriver_monitoring_metric_types_scraped"].GetMetric()[0].GetGauge().GetValue(); got != 2 {
+		t.Errorf("Expected 2 metric types scraped, got %v", got)
+	}
+}
+
+func TestDescriptorProfileMetrics(t *testing.T) {
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{
+			newTestDescriptor("custom.googleapis.com/a", "GAUGE", "DOUBLE"),
+			newTestDescriptor("custom.googleapis.com/b", "GAUGE", "INT64"),
+			newTestDescriptor("custom.googleapis.com/c", "DELTA", "INT64"),
+			newTestDescriptor("custom.googleapis.com/d", "DELTA", "DISTRIBUTION"),
+			newTestDescriptor("custom.googleapis.com/e", "CUMULATIVE", "DISTRIBUTION"),
+			newTestDescriptor("custom.googleapis.com/f", "CUMULATIVE", "INT64"),
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:       []string{"custom.googleapis.com"},
+		RequestInterval:          5 * time.Minute,
+		DescriptorProfileMetrics: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+
+	counts := func(name, label string) map[string]float64 {
+		result := make(map[string]float64)
+		for _, metric := range families[name].GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() == label {
+					result[pair.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+		return result
+	}
+
+	expectedValueTypes := map[string]float64{"DOUBLE": 1, "INT64": 3, "DISTRIBUTION": 2}
+	if got := counts("stackdriver_monitoring_descriptors_by_value_type", "value_type"); !reflect.DeepEqual(got, expectedValueTypes) {
+		t.Errorf("Expected descriptors by value type %v, got %v", expectedValueTypes, got)
+	}
+	expectedMetricKinds := map[string]float64{"GAUGE": 2, "DELTA": 2, "CUMULATIVE": 2}
+	if got := counts("stackdriver_monitoring_descriptors_by_metric_kind", "metric_kind"); !reflect.DeepEqual(got, expectedMetricKinds) {
+		t.Errorf("Expected descriptors by metric kind %v, got %v", expectedMetricKinds, got)
+	}
+}
+
+func TestAlignmentPeriodNormalization(t *testing.T) {
+	configs := []MetricAggregationConfig{{TargetedMetricPrefix: "pubsub.googleapis.com", AlignmentPeriod: "60"}}
+	collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		MetricAggregationConfigs: configs,
+		RequestInterval:          5 * time.Minute,
+	}, slog.Default(), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	if got := collector.metricsAggregationConfigs[0].AlignmentPeriod; got != "60s" {
+		t.Errorf("Expected alignment period to be normalized to 60s, got %q", got)
+	}
+	if configs[0].AlignmentPeriod != "60" {
+		t.Errorf("Expected the options not to be modified, got %q", configs[0].AlignmentPeriod)
+	}
+}
+
+func TestValueTypeAligners(t *testing.T) {
+	descriptors := []*monitoring.MetricDescriptor{
+		newTestDescriptor("custom.googleapis.com/latency", "DELTA", "DISTRIBUTION"),
+		newTestDescriptor("custom.googleapis.com/utilization", "GAUGE", "DOUBLE"),
+		newTestDescriptor("custom.googleapis.com/requests", "CUMULATIVE", "INT64"),
+		newTestDescriptor("custom.googleapis.com/sizes", "GAUGE", "DISTRIBUTION"),
+		newTestDescriptor("other.googleapis.com/utilization", "GAUGE", "DOUBLE"),
+	}
+
+	tests := []struct {
+		name     string
+		aligner  string
+		aligners map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "default aligners by metric kind and value type",
+			expected: map[string]string{
+				"custom.googleapis.com/latency":     "ALIGN_DELTA",
+				"custom.googleapis.com/utilization": "ALIGN_MEAN",
+				"custom.googleapis.com/requests":    "",
+				"custom.googleapis.com/sizes":       "",
+				"other.googleapis.com/utilization":  "",
+			},
+		},
+		{
+			name:     "aligners by value type apply to every metric kind",
+			aligners: map[string]string{"GAUGE:DOUBLE": "ALIGN_MAX", "INT64": "ALIGN_RATE"},
+			expected: map[string]string{
+				"custom.googleapis.com/latency":     "",
+				"custom.googleapis.com/utilization": "ALIGN_MAX",
+				"custom.googleapis.com/requests":    "ALIGN_RATE",
+				"custom.googleapis.com/sizes":       "",
+				"other.googleapis.com/utilization":  "",
+			},
+		},
+		{
+			name:    "explicit aligner overrides",
+			aligner: "ALIGN_MAX",
+			expected: map[string]string{
+				"custom.googleapis.com/latency":     "ALIGN_MAX",
+				"custom.googleapis.com/utilization": "ALIGN_MAX",
+				"custom.googleapis.com/requests":    "ALIGN_MAX",
+				"custom.googleapis.com/sizes":       "ALIGN_MAX",
+				"other.googleapis.com/utilization":  "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeMonitoringAPI{descriptors: descriptors}
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com", "other.googleapis.com"},
+				MetricAggregationConfigs: []MetricAggregationConfig{
+					{TargetedMetricPrefix: "custom.googleapis.com", AlignmentPeriod: "60s", PerSeriesAligner: tt.aligner},
+				},
+				ValueTypeAligners: tt.aligners,
+				RequestInterval:   5 * time.Minute,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+			gatherFamilies(t, collector)
+
+			aligners := make(map[string]string)
+			for _, request := range api.timeSeriesRequests {
+				if m := fakeMetricTypeRE.FindStringSubmatch(request.Get("filter")); m != nil {
+					aligners[m[1]] = request.Get("aggregation.perSeriesAligner")
+				}
+			}
+			if !reflect.DeepEqual(aligners, tt.expected) {
+				t.Errorf("Expected aligners %v, got %v", tt.expected, aligners)
+			}
+		})
+	}
+}
+
+func TestDescriptorCacheBackgroundRefresh(t *testing.T) {
+	metricType := "custom.googleapis.com/a"
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:               []string{"custom.googleapis.com"},
+		RequestInterval:                  5 * time.Minute,
+		DescriptorCacheTTL:               50 * time.Millisecond,
+		DescriptorCacheBackgroundRefresh: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	// waitForRefresh waits until no background refresh is in flight
+	waitForRefresh := func() {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			refreshing := false
+			collector.descriptorCacheRefreshing.Range(func(key, value any) bool {
+				refreshing = true
+				return false
+			})
+			if !refreshing {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the background refresh")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	gatherFamilies(t, collector)
+	time.Sleep(100 * time.Millisecond)
+
+	api.mu.Lock()
+	api.descriptorsStatus = http.StatusInternalServerError
+	api.mu.Unlock()
+
+	// The expired descriptors are used while the refresh fails in the background
+	families := gatherFamilies(t, collector)
+	waitForRefresh()
+
+	if got := families["stackdriver_monitoring_last_scrape_error"].GetMetric()[0].GetGauge().GetValue(); got != 0 {
+		t.Errorf("Expected no scrape error while refreshing in the background, got %v", got)
+	}
+	if _, ok := families["stackdriver_gce_instance_custom_googleapis_com_a"]; !ok {
+		t.Error("Expected the metric to be reported with the stale descriptors")
+	}
+	if got := testutil.ToFloat64(collector.descriptorCacheRefreshErrors.WithLabelValues("google")); got != 1 {
+		t.Errorf("Expected 1 refresh error, got %v", got)
+	}
+	if cached := collector.descriptorCache.Lookup("custom.googleapis.com"); cached != nil {
+		t.Error("Expected the cache entry to stay expired after a failed refresh")
+	}
+
+	api.mu.Lock()
+	api.descriptorsStatus = 0
+	api.mu.Unlock()
+
+	gatherFamilies(t, collector)
+	waitForRefresh()
+
+	if got := testutil.ToFloat64(collector.descriptorCacheRefreshErrors.WithLabelValues("google")); got != 1 {
+		t.Errorf("Expected the refresh errors to stay at 1, got %v", got)
+	}
+	if cached := collector.descriptorCache.Lookup("custom.googleapis.com"); cached == nil {
+		t.Error("Expected the cache entry to be refreshed")
+	}
+}
+
+func TestMetricTypePolicy(t *testing.T) {
+	metricType := "custom.googleapis.com/value"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_value"
+
+	// reportedType returns the type of the metric reported for a time series of the given kind, or nil if discarded
+	reportedType := func(t *testing.T, policy MetricTypePolicy, metricKind string, aggregateDeltas bool) *dto.MetricType {
+		t.Helper()
+		collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+			RequestInterval:  5 * time.Minute,
+			AggregateDeltas:  aggregateDeltas,
+			MetricTypePolicy: policy,
+		}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+		if err != nil {
+			t.Fatalf("Failed to create collector: %v", err)
+		}
+
+		page := &monitoring.ListTimeSeriesResponse{
+			TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, metricKind, 1, time.Now())},
+		}
+		metrics := reportPage(t, collector, page, newTestDescriptor(metricType, metricKind, "DOUBLE"))
+		if len(metrics[fqName]) == 0 {
+			return nil
+		}
+		reported := dto.MetricType_GAUGE
+		if metrics[fqName][0].GetCounter() != nil {
+			reported = dto.MetricType_COUNTER
+		}
+		return &reported
+	}
+
+	tests := []struct {
+		name            string
+		policy          MetricTypePolicy
+		metricKind      string
+		aggregateDeltas bool
+		expected        *dto.MetricType
+	}{
+		{name: "default gauge", metricKind: "GAUGE", expected: dto.MetricType_GAUGE.Enum()},
+		{name: "default delta", metricKind: "DELTA", expected: dto.MetricType_GAUGE.Enum()},
+		{name: "default aggregated delta", metricKind: "DELTA", aggregateDeltas: true, expected: dto.MetricType_COUNTER.Enum()},
+		{name: "default cumulative", metricKind: "CUMULATIVE", expected: dto.MetricType_COUNTER.Enum()},
+		{name: "default unknown kind", metricKind: "METRIC_KIND_UNSPECIFIED", expected: nil},
+		{
+			name:       "custom gauge as counter",
+			policy:     MetricTypePolicy{{MetricKind: "GAUGE"}: prometheus.CounterValue},
+			metricKind: "GAUGE",
+			expected:   dto.MetricType_COUNTER.Enum(),
+		},
+		{
+			name:       "custom cumulative as gauge",
+			policy:     MetricTypePolicy{{MetricKind: "CUMULATIVE"}: prometheus.GaugeValue},
+			metricKind: "CUMULATIVE",
+			expected:   dto.MetricType_GAUGE.Enum(),
+		},
+		{
+			name:       "custom kind missing from the policy",
+			policy:     MetricTypePolicy{{MetricKind: "GAUGE"}: prometheus.GaugeValue},
+			metricKind: "CUMULATIVE",
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reportedType(t, tt.policy, tt.metricKind, tt.aggregateDeltas)
+			if tt.expected == nil {
+				if got != nil {
+					t.Errorf("Expected the time series to be discarded, got %v", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Expected a %v, got the time series discarded", *tt.expected)
+			}
+			if *got != *tt.expected {
+				t.Errorf("Expected a %v, got %v", *tt.expected, *got)
+			}
+		})
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:  5 * time.Minute,
+		MetricTypePolicy: MetricTypePolicy{{MetricKind: "DELTA"}: prometheus.CounterValue},
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for DELTA metrics which are not aggregated reported as counters")
+	}
+}
+
+func TestCreatedTimestamps(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	descriptor := newTestDescriptor(metricType, "DELTA", "DOUBLE")
+
+	for _, createdTimestamps := range []bool{false, true} {
+		t.Run(strconv.FormatBool(createdTimestamps), func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				RequestInterval:   5 * time.Minute,
+				AggregateDeltas:   true,
+				CreatedTimestamps: createdTimestamps,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			start := time.Now().Add(-time.Hour)
+			var createdTimestamp *time.Time
+			for i := 0; i < 2; i++ {
+				page := &monitoring.ListTimeSeriesResponse{
+					TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "DELTA", 1, start.Add(time.Duration(i)*time.Minute))},
+				}
+				metrics := reportPage(t, collector, page, descriptor)
+				if len(metrics[fqName]) != 1 {
+					t.Fatalf("Scrape %d: expected 1 %s metric, got %d", i, fqName, len(metrics[fqName]))
+				}
+				counter := metrics[fqName][0].GetCounter()
+
+				if !createdTimestamps {
+					if counter.GetCreatedTimestamp() != nil {
+						t.Errorf("Scrape %d: expected no created timestamp, got %v", i, counter.GetCreatedTimestamp().AsTime())
+					}
+					continue
+				}
+				if counter.GetCreatedTimestamp() == nil {
+					t.Fatalf("Scrape %d: expected a created timestamp", i)
+				}
+				got := counter.GetCreatedTimestamp().AsTime()
+				if createdTimestamp != nil && !got.Equal(*createdTimestamp) {
+					t.Errorf("Scrape %d: expected the created timestamp to stay %v, got %v", i, *createdTimestamp, got)
+				}
+				createdTimestamp = &got
+			}
+		})
+	}
+}
+
+func TestDeltaCounterPrefixes(t *testing.T) {
+	metricType := "logging.googleapis.com/user/errors"
+	fqName := "stackdriver_gce_instance_logging_googleapis_com_user_errors"
+	descriptor := newTestDescriptor(metricType, "DELTA", "INT64")
+
+	tests := []struct {
+		name            string
+		prefixes        []string
+		expectedCounter bool
+	}{
+		{name: "default prefixes", prefixes: nil, expectedCounter: true},
+		{name: "disabled", prefixes: []string{}, expectedCounter: false},
+		{name: "other prefixes", prefixes: []string{"custom.googleapis.com"}, expectedCounter: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				RequestInterval:      5 * time.Minute,
+				DeltaCounterPrefixes: tt.prefixes,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			timeSeries := newTestTimeSeries(metricType, "DELTA", 0, time.Now().Add(-time.Minute))
+			timeSeries.ValueType = "INT64"
+			value := int64(3)
+			timeSeries.Points[0].Value = &monitoring.TypedValue{Int64Value: &value}
+			page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{timeSeries}}
+
+			metrics := reportPage(t, collector, page, descriptor)[fqName]
+			if len(metrics) != 1 {
+				t.Fatalf("Expected 1 metric, got %d", len(metrics))
+			}
+			if tt.expectedCounter {
+				if metrics[0].GetCounter() == nil || metrics[0].GetCounter().GetValue() != 3 {
+					t.Errorf("Expected a counter with value 3, got %v", metrics[0])
+				}
+			} else if metrics[0].GetGauge() == nil {
+				t.Errorf("Expected a gauge, got %v", metrics[0])
+			}
+		})
+	}
+}
+
+// gatedAPI holds the time series requests until its gate is closed, once blocking.
+type gatedAPI struct {
+	api      http.Handler
+	blocking atomic.Bool
+	gate     chan struct{}
+	entered  chan struct{}
+}
+
+func (g *gatedAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.blocking.Load() && strings.HasSuffix(r.URL.Path, "/timeSeries") {
+		g.entered <- struct{}{}
+		<-g.gate
+	}
+	g.api.ServeHTTP(w, r)
+}
+
+func TestOverlappingScrapes(t *testing.T) {
+	metricType := "custom.googleapis.com/a"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_a"
+
+	collectMetrics := func(collector prometheus.Collector) <-chan []string {
+		result := make(chan []string, 1)
+		go func() {
+			ch := make(chan prometheus.Metric)
+			go func() {
+				collector.Collect(ch)
+				close(ch)
+			}()
+			var names []string
+			for metric := range ch {
+				names = append(names, fqNameRE.FindStringSubmatch(metric.Desc().String())[1])
+			}
+			result <- names
+		}()
+		return result
+	}
+
+	for _, policy := range []string{OverlappingScrapesConcurrent, OverlappingScrapesSerialize, OverlappingScrapesReject} {
+		t.Run(policy, func(t *testing.T) {
+			api := &gatedAPI{
+				api: &fakeMonitoringAPI{
+					descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+					timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+						metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+					},
+				},
+				gate:    make(chan struct{}),
+				entered: make(chan struct{}, 2),
+			}
+			collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				OverlappingScrapes: policy,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+			gatherFamilies(t, collector)
+
+			api.blocking.Store(true)
+			first := collectMetrics(collector)
+			<-api.entered
+			second := collectMetrics(collector)
+			pending := []<-chan []string{first, second}
+
+			switch policy {
+			case OverlappingScrapesConcurrent:
+				select {
+				case <-api.entered:
+				case <-time.After(5 * time.Second):
+					t.Fatal("Expected the overlapping scrape to run concurrently")
+				}
+			case OverlappingScrapesSerialize:
+				select {
+				case <-api.entered:
+					t.Fatal("Expected the overlapping scrape to wait for the scrape in progress")
+				case <-time.After(100 * time.Millisecond):
+				}
+			case OverlappingScrapesReject:
+				select {
+				case names := <-second:
+					if !slices.Contains(names, fqName) {
+						t.Errorf("Expected the metrics of the last scrape to be reported, got %v", names)
+					}
+					pending = pending[:1]
+				case <-time.After(5 * time.Second):
+					t.Fatal("Expected the overlapping scrape to return without waiting")
+				}
+			}
+
+			close(api.gate)
+			for _, result := range pending {
+				select {
+				case <-result:
+				case <-time.After(5 * time.Second):
+					t.Fatal("Expected the scrapes to complete")
+				}
+			}
+
+			if got := testutil.ToFloat64(collector.overlappingScrapesTotalMetric); got != 1 {
+				t.Errorf("Expected overlapping scrapes counter to be 1, got %v", got)
+			}
+		})
+	}
+
+	if _, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+		RequestInterval:    5 * time.Minute,
+		OverlappingScrapes: "queue",
+	}, slog.Default(), nil, nil); err == nil {
+		t.Error("Expected an error for an unknown overlapping scrapes policy")
+	}
+}
+
+func TestNameSuffixes(t *testing.T) {
+	tests := []struct {
+		name       string
+		metricType string
+		metricKind string
+		unit       string
+		expected   string
+	}{
+		{
+			name:       "counter",
+			metricType: "custom.googleapis.com/requests",
+			metricKind: "CUMULATIVE",
+			expected:   "stackdriver_gce_instance_custom_googleapis_com_requests_total",
+		},
+		{
+			name:       "bytes gauge",
+			metricType: "custom.googleapis.com/memory/used",
+			metricKind: "GAUGE",
+			unit:       "By",
+			expected:   "stackdriver_gce_instance_custom_googleapis_com_memory_used_bytes",
+		},
+		{
+			name:       "seconds counter",
+			metricType: "custom.googleapis.com/cpu/usage_time",
+			metricKind: "CUMULATIVE",
+			unit:       "s",
+			expected:   "stackdriver_gce_instance_custom_googleapis_com_cpu_usage_time_seconds_total",
+		},
+		{
+			name:       "existing suffixes",
+			metricType: "custom.googleapis.com/sent_bytes_total",
+			metricKind: "CUMULATIVE",
+			unit:       "By",
+			expected:   "stackdriver_gce_instance_custom_googleapis_com_sent_bytes_total",
+		},
+		{
+			name:       "unit needing a conversion",
+			metricType: "custom.googleapis.com/latency",
+			metricKind: "GAUGE",
+			unit:       "ms",
+			expected:   "stackdriver_gce_instance_custom_googleapis_com_latency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				RequestInterval: 5 * time.Minute,
+				NameSuffixes:    true,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			descriptor := newTestDescriptor(tt.metricType, tt.metricKind, "DOUBLE")
+			descriptor.Unit = tt.unit
+			page := &monitoring.ListTimeSeriesResponse{
+				TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(tt.metricType, tt.metricKind, 1, time.Now())},
+			}
+			metrics := reportPage(t, collector, page, descriptor)
+			if _, ok := metrics[tt.expected]; !ok || len(metrics) != 1 {
+				t.Errorf("Expected metric %s, got %v", tt.expected, slices.Collect(maps.Keys(metrics)))
+			}
+		})
+	}
+}
+
+func TestSeparateInternalMetrics(t *testing.T) {
+	metricType := "custom.googleapis.com/a"
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:      []string{"custom.googleapis.com"},
+		RequestInterval:         5 * time.Minute,
+		SeparateInternalMetrics: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	families := gatherFamilies(t, collector)
+	if _, ok := families["stackdriver_gce_instance_custom_googleapis_com_a"]; !ok {
+		t.Error("Expected the scraped metric to be reported by the collector")
+	}
+	for name := range families {
+		if strings.HasPrefix(name, "stackdriver_monitoring_") {
+			t.Errorf("Expected internal metric %s not to be reported by the collector", name)
+		}
+	}
+
+	internalFamilies := gatherFamilies(t, collector.InternalCollector())
+	if _, ok := internalFamilies["stackdriver_gce_instance_custom_googleapis_com_a"]; ok {
+		t.Error("Expected the scraped metric not to be reported by the internal collector")
+	}
+	if got := internalFamilies["stackdriver_monitoring_scrapes_total"].GetMetric()[0].GetCounter().GetValue(); got != 1 {
+		t.Errorf("Expected 1 scrape in the internal registry, got %v", got)
+	}
+	if got := internalFamilies["stackdriver_monitoring_api_calls_total"].GetMetric()[0].GetCounter().GetValue(); got != 2 {
+		t.Errorf("Expected 2 API calls in the internal registry, got %v", got)
+	}
+}
+
+func TestOldestAPICallMetric(t *testing.T) {
+	metricType := "custom.googleapis.com/a"
+	api := &gatedAPI{
+		api: &fakeMonitoringAPI{
+			descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+			timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+				metricType: {{TimeSeries: []*monitoring.TimeSeries{newTestTimeSeries(metricType, "GAUGE", 1, time.Now())}}},
+			},
+		},
+		gate:    make(chan struct{}),
+		entered: make(chan struct{}, 1),
+	}
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:  []string{"custom.googleapis.com"},
+		RequestInterval:     5 * time.Minute,
+		OldestAPICallMetric: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	if got := testutil.ToFloat64(collector.oldestAPICallAgeMetric); got != 0 {
+		t.Errorf("Expected no API call in progress before scraping, got an age of %v", got)
+	}
+
+	api.blocking.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ch := make(chan prometheus.Metric)
+		go func() {
+			collector.Collect(ch)
+			close(ch)
+		}()
+		for range ch {
+		}
+	}()
+
+	<-api.entered
+	time.Sleep(50 * time.Millisecond)
+	if got := testutil.ToFloat64(collector.oldestAPICallAgeMetric); got < 0.05 {
+		t.Errorf("Expected the age of the slow API call to be at least 0.05s, got %v", got)
+	}
+
+	close(api.gate)
+	<-done
+	if got := testutil.ToFloat64(collector.oldestAPICallAgeMetric); got != 0 {
+		t.Errorf("Expected no API call in progress after scraping, got an age of %v", got)
+	}
+}
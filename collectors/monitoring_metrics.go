@@ -14,6 +14,8 @@
 package collectors
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -33,18 +35,28 @@ func buildFQName(timeSeries *monitoring.TimeSeries) string {
 	return prometheus.BuildFQName(namespace, utils.NormalizeMetricName(timeSeries.Resource.Type), utils.NormalizeMetricName(timeSeries.Metric.Type))
 }
 
+// unitSuffixes are the Prometheus base unit suffixes of the metric descriptor units which need no conversion.
+var unitSuffixes = map[string]string{
+	"By": "bytes",
+	"s":  "seconds",
+}
+
 type timeSeriesMetrics struct {
 	metricDescriptor *monitoring.MetricDescriptor
 
 	ch chan<- prometheus.Metric
+	// families counts the metrics sent by family name, it is nil when nothing consumes the counts
+	families map[string]int
 
 	fillMissingLabels bool
 	constMetrics      map[string][]*ConstMetric
 	histogramMetrics  map[string][]*HistogramMetric
 
-	counterStore    DeltaCounterStore
-	histogramStore  DeltaHistogramStore
-	aggregateDeltas bool
+	counterStore      DeltaCounterStore
+	histogramStore    DeltaHistogramStore
+	aggregateDeltas   bool
+	createdTimestamps bool
+	nameSuffixes      bool
 }
 
 func newTimeSeriesMetrics(descriptor *monitoring.MetricDescriptor,
@@ -52,20 +64,54 @@ func newTimeSeriesMetrics(descriptor *monitoring.MetricDescriptor,
 	fillMissingLabels bool,
 	counterStore DeltaCounterStore,
 	histogramStore DeltaHistogramStore,
-	aggregateDeltas bool) (*timeSeriesMetrics, error) {
-
+	aggregateDeltas bool,
+	createdTimestamps bool,
+	nameSuffixes bool,
+	countFamilies bool) (*timeSeriesMetrics, error) {
+
+	var families map[string]int
+	if countFamilies {
+		families = make(map[string]int)
+	}
 	return &timeSeriesMetrics{
 		metricDescriptor:  descriptor,
 		ch:                ch,
+		families:          families,
 		fillMissingLabels: fillMissingLabels,
 		constMetrics:      make(map[string][]*ConstMetric),
 		histogramMetrics:  make(map[string][]*HistogramMetric),
 		counterStore:      counterStore,
 		histogramStore:    histogramStore,
 		aggregateDeltas:   aggregateDeltas,
+		createdTimestamps: createdTimestamps,
+		nameSuffixes:      nameSuffixes,
 	}, nil
 }
 
+// send reports a metric of a family and counts it, if the families are counted.
+func (t *timeSeriesMetrics) send(fqName string, metric prometheus.Metric) {
+	if t.families != nil {
+		t.families[fqName]++
+	}
+	t.ch <- metric
+}
+
+// metricName returns the name of the metric reported for a time series. When name suffixes are enabled, the unit of
+// the metric descriptor and `_total` for counters are appended unless the name already has them.
+func (t *timeSeriesMetrics) metricName(timeSeries *monitoring.TimeSeries, counter bool) string {
+	fqName := buildFQName(timeSeries)
+	if !t.nameSuffixes {
+		return fqName
+	}
+	if suffix, ok := unitSuffixes[t.metricDescriptor.Unit]; ok && !slices.Contains(strings.Split(fqName, "_"), suffix) {
+		fqName += "_" + suffix
+	}
+	if counter && !strings.HasSuffix(fqName, "_total") {
+		fqName += "_total"
+	}
+	return fqName
+}
+
 func (t *timeSeriesMetrics) newMetricDesc(fqName string, labelKeys []string) *prometheus.Desc {
 	return prometheus.NewDesc(
 		fqName,
@@ -83,6 +129,8 @@ type ConstMetric struct {
 	LabelValues    []string
 	ReportTime     time.Time
 	CollectionTime time.Time
+	// CreatedTime is when the counter store started accumulating the counter, it is zero for other metrics.
+	CreatedTime time.Time
 
 	KeysHash uint64
 }
@@ -111,8 +159,10 @@ func (h *HistogramMetric) MergeHistogram(other *HistogramMetric) {
 	}
 }
 
+// CollectNewConstHistogram reports a distribution as a histogram. Only the DELTA distributions are accumulated by the
+// histogram store when aggregating deltas, the GAUGE and CUMULATIVE ones are reported as is.
 func (t *timeSeriesMetrics) CollectNewConstHistogram(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, dist *monitoring.Distribution, buckets map[float64]uint64, labelValues []string, metricKind string) {
-	fqName := buildFQName(timeSeries)
+	fqName := t.metricName(timeSeries, false)
 	histogramSum := dist.Mean * float64(dist.Count)
 	var v HistogramMetric
 	if t.fillMissingLabels || (metricKind == "DELTA" && t.aggregateDeltas) {
@@ -144,7 +194,30 @@ func (t *timeSeriesMetrics) CollectNewConstHistogram(timeSeries *monitoring.Time
 		return
 	}
 
-	t.ch <- t.newConstHistogram(fqName, reportTime, labelKeys, histogramSum, uint64(dist.Count), buckets, labelValues)
+	t.send(fqName, t.newConstHistogram(fqName, reportTime, labelKeys, histogramSum, uint64(dist.Count), buckets, labelValues))
+}
+
+// CollectNativeHistogram reports an exponential distribution as a native histogram of a schema.
+func (t *timeSeriesMetrics) CollectNativeHistogram(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, dist *monitoring.Distribution, schema int32, labelValues []string) error {
+	positive, zeroCount, zeroThreshold := nativeHistogramBuckets(dist.BucketOptions.ExponentialBuckets, dist.BucketCounts, schema)
+	fqName := t.metricName(timeSeries, false)
+	histogram, err := prometheus.NewConstNativeHistogram(
+		t.newMetricDesc(fqName, labelKeys),
+		uint64(dist.Count),
+		dist.Mean*float64(dist.Count),
+		positive,
+		nil,
+		zeroCount,
+		schema,
+		zeroThreshold,
+		time.Time{},
+		labelValues...,
+	)
+	if err != nil {
+		return err
+	}
+	t.send(fqName, prometheus.NewMetricWithTimestamp(reportTime, histogram))
+	return nil
 }
 
 func (t *timeSeriesMetrics) newConstHistogram(fqName string, reportTime time.Time, labelKeys []string, sum float64, count uint64, buckets map[float64]uint64, labelValues []string) prometheus.Metric {
@@ -161,8 +234,19 @@ func (t *timeSeriesMetrics) newConstHistogram(fqName string, reportTime time.Tim
 }
 
 func (t *timeSeriesMetrics) CollectNewConstMetric(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string, metricKind string) {
-	fqName := buildFQName(timeSeries)
+	t.collectConstMetric(t.metricName(timeSeries, metricValueType == prometheus.CounterValue), reportTime, labelKeys, metricValueType, metricValue, labelValues, metricKind)
+}
+
+// CollectDistributionFallback reports the count and sum of a distribution as `<metric>_count` and `<metric>_sum`
+// metrics when no histogram could be generated from it.
+func (t *timeSeriesMetrics) CollectDistributionFallback(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, dist *monitoring.Distribution, labelValues []string, metricKind string) {
+	fqName := t.metricName(timeSeries, false)
+	// The label slices are copied as filling the labels appends to them
+	t.collectConstMetric(fqName+"_count", reportTime, append([]string{}, labelKeys...), metricValueType, float64(dist.Count), append([]string{}, labelValues...), metricKind)
+	t.collectConstMetric(fqName+"_sum", reportTime, append([]string{}, labelKeys...), metricValueType, dist.Mean*float64(dist.Count), append([]string{}, labelValues...), metricKind)
+}
 
+func (t *timeSeriesMetrics) collectConstMetric(fqName string, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string, metricKind string) {
 	var v ConstMetric
 	if t.fillMissingLabels || (metricKind == "DELTA" && t.aggregateDeltas) {
 		v = ConstMetric{
@@ -192,7 +276,48 @@ func (t *timeSeriesMetrics) CollectNewConstMetric(timeSeries *monitoring.TimeSer
 		return
 	}
 
-	t.ch <- t.newConstMetric(fqName, reportTime, labelKeys, metricValueType, metricValue, labelValues)
+	t.send(fqName, t.newConstMetric(fqName, reportTime, labelKeys, metricValueType, metricValue, labelValues))
+}
+
+// CollectGaugeIncrement feeds the increment of a GAUGE series converted to a counter into the counter store.
+func (t *timeSeriesMetrics) CollectGaugeIncrement(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, increment float64, labelValues []string) {
+	// The label slices are retained by the counter store, copy them as filling the labels appends to them
+	t.counterStore.Increment(t.metricDescriptor, &ConstMetric{
+		FqName:         t.metricName(timeSeries, true),
+		LabelKeys:      append([]string{}, labelKeys...),
+		ValueType:      prometheus.CounterValue,
+		Value:          increment,
+		LabelValues:    append([]string{}, labelValues...),
+		ReportTime:     reportTime,
+		CollectionTime: time.Now(),
+
+		KeysHash: hashLabelKeys(labelKeys),
+	})
+}
+
+// CollectLastSeen reports the end time of the newest point of a time series as a `<metric>_last_seen_seconds` gauge.
+func (t *timeSeriesMetrics) CollectLastSeen(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, labelValues []string) {
+	fqName := buildFQName(timeSeries) + "_last_seen_seconds"
+	lastSeen := float64(reportTime.UnixNano()) / 1e9
+
+	if t.fillMissingLabels {
+		// The label slices are shared with the time series metric, copy them as filling the labels appends to them
+		v := &ConstMetric{
+			FqName:         fqName,
+			LabelKeys:      append([]string{}, labelKeys...),
+			ValueType:      prometheus.GaugeValue,
+			Value:          lastSeen,
+			LabelValues:    append([]string{}, labelValues...),
+			ReportTime:     reportTime,
+			CollectionTime: time.Now(),
+
+			KeysHash: hashLabelKeys(labelKeys),
+		}
+		t.constMetrics[fqName] = append(t.constMetrics[fqName], v)
+		return
+	}
+
+	t.send(fqName, t.newConstMetric(fqName, reportTime, labelKeys, prometheus.GaugeValue, lastSeen, labelValues))
 }
 
 func (t *timeSeriesMetrics) newConstMetric(fqName string, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string) prometheus.Metric {
@@ -207,6 +332,24 @@ func (t *timeSeriesMetrics) newConstMetric(fqName string, reportTime time.Time,
 	)
 }
 
+// newStoredConstMetric returns the metric of a ConstMetric, with its created timestamp for the counters accumulated by
+// the counter store if enabled.
+func (t *timeSeriesMetrics) newStoredConstMetric(v *ConstMetric) prometheus.Metric {
+	if !t.createdTimestamps || v.CreatedTime.IsZero() || v.ValueType != prometheus.CounterValue {
+		return t.newConstMetric(v.FqName, v.ReportTime, v.LabelKeys, v.ValueType, v.Value, v.LabelValues)
+	}
+	return prometheus.NewMetricWithTimestamp(
+		v.ReportTime,
+		prometheus.MustNewConstMetricWithCreatedTimestamp(
+			t.newMetricDesc(v.FqName, v.LabelKeys),
+			v.ValueType,
+			v.Value,
+			v.CreatedTime,
+			v.LabelValues...,
+		),
+	)
+}
+
 func hashLabelKeys(labelKeys []string) uint64 {
 	dh := hash.New()
 	sortedKeys := make([]string, len(labelKeys))
@@ -241,7 +384,7 @@ func (t *timeSeriesMetrics) completeConstMetrics(constMetrics map[string][]*Cons
 		}
 
 		for _, v := range vs {
-			t.ch <- t.newConstMetric(v.FqName, v.ReportTime, v.LabelKeys, v.ValueType, v.Value, v.LabelValues)
+			t.send(v.FqName, t.newStoredConstMetric(v))
 		}
 	}
 }
@@ -260,7 +403,7 @@ func (t *timeSeriesMetrics) completeHistogramMetrics(histograms map[string][]*Hi
 			}
 		}
 		for _, v := range vs {
-			t.ch <- t.newConstHistogram(v.FqName, v.ReportTime, v.LabelKeys, v.Sum, v.Count, v.Buckets, v.LabelValues)
+			t.send(v.FqName, t.newConstHistogram(v.FqName, v.ReportTime, v.LabelKeys, v.Sum, v.Count, v.Buckets, v.LabelValues))
 		}
 	}
 }
@@ -285,14 +428,7 @@ func (t *timeSeriesMetrics) completeDeltaConstMetrics(reportingStartTime time.Ti
 			}
 			constMetrics[collected.FqName] = append(constMetrics[collected.FqName], collected)
 		} else {
-			t.ch <- t.newConstMetric(
-				collected.FqName,
-				collected.ReportTime,
-				collected.LabelKeys,
-				collected.ValueType,
-				collected.Value,
-				collected.LabelValues,
-			)
+			t.send(collected.FqName, t.newStoredConstMetric(collected))
 		}
 	}
 
@@ -321,7 +457,7 @@ func (t *timeSeriesMetrics) completeDeltaHistogramMetrics(reportingStartTime tim
 			}
 			histograms[collected.FqName] = append(histograms[collected.FqName], collected)
 		} else {
-			t.ch <- t.newConstHistogram(
+			t.send(collected.FqName, t.newConstHistogram(
 				collected.FqName,
 				collected.ReportTime,
 				collected.LabelKeys,
@@ -329,7 +465,7 @@ func (t *timeSeriesMetrics) completeDeltaHistogramMetrics(reportingStartTime tim
 				collected.Count,
 				collected.Buckets,
 				collected.LabelValues,
-			)
+			))
 		}
 	}
 
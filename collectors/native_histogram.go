@@ -0,0 +1,59 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+const (
+	nativeHistogramSchemaMin = -4
+	nativeHistogramSchemaMax = 8
+)
+
+// nativeHistogramSchema returns the native histogram schema whose bucket growth, 2^(2^-schema), is the closest to the
+// growth factor of an exponential distribution. It is exact when the growth factor is a power of 2 within the
+// supported schemas, otherwise the buckets are approximated.
+func nativeHistogramSchema(growthFactor float64) (schema int32, exact bool, err error) {
+	if !(growthFactor > 1) || math.IsInf(growthFactor, 1) {
+		return 0, false, fmt.Errorf("invalid growth factor %v", growthFactor)
+	}
+	precise := -math.Log2(math.Log2(growthFactor))
+	rounded := math.Max(nativeHistogramSchemaMin, math.Min(nativeHistogramSchemaMax, math.Round(precise)))
+	return int32(rounded), math.Abs(precise-rounded) < 1e-9, nil
+}
+
+// nativeHistogramBuckets maps the buckets of an exponential distribution to the buckets of a native histogram schema.
+// Each finite bucket is counted in the native bucket of its geometric middle, and the overflow bucket in the native
+// bucket following the last finite one. The underflow bucket is counted in the zero bucket, whose threshold is the
+// scale of the distribution. The mapping is exact when the growth factor and the scale are powers of the schema base.
+func nativeHistogramBuckets(exponential *monitoring.Exponential, bucketCounts []int64, schema int32) (positive map[int]int64, zeroCount uint64, zeroThreshold float64) {
+	positive = make(map[int]int64)
+	factor := math.Exp2(float64(schema))
+	for i, count := range bucketCounts {
+		if count == 0 {
+			continue
+		}
+		if i == 0 {
+			zeroCount += uint64(count)
+			continue
+		}
+		middle := exponential.Scale * math.Pow(exponential.GrowthFactor, float64(i)-0.5)
+		positive[int(math.Ceil(math.Log2(middle)*factor))] += count
+	}
+	return positive, zeroCount, exponential.Scale
+}
@@ -0,0 +1,218 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"maps"
+	"math"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestNativeHistogramSchema(t *testing.T) {
+	tests := []struct {
+		growthFactor float64
+		schema       int32
+		exact        bool
+	}{
+		{growthFactor: 2, schema: 0, exact: true},
+		{growthFactor: 4, schema: -1, exact: true},
+		{growthFactor: 16, schema: -2, exact: true},
+		{growthFactor: 65536, schema: -4, exact: true},
+		{growthFactor: math.Sqrt2, schema: 1, exact: true},
+		{growthFactor: math.Pow(2, 1.0/256), schema: 8, exact: true},
+		{growthFactor: 10, schema: -2},
+		{growthFactor: 1.5, schema: 1},
+		{growthFactor: 1.1, schema: 3},
+		{growthFactor: 1.0001, schema: 8},
+		{growthFactor: 1e300, schema: -4},
+	}
+
+	for _, tt := range tests {
+		schema, exact, err := nativeHistogramSchema(tt.growthFactor)
+		if err != nil {
+			t.Errorf("Unexpected error for growth factor %v: %v", tt.growthFactor, err)
+			continue
+		}
+		if schema != tt.schema || exact != tt.exact {
+			t.Errorf("Expected growth factor %v to map to schema %d (exact %v), got %d (exact %v)", tt.growthFactor, tt.schema, tt.exact, schema, exact)
+		}
+	}
+
+	for _, growthFactor := range []float64{1, 0.5, 0, math.NaN(), math.Inf(1)} {
+		if _, _, err := nativeHistogramSchema(growthFactor); err == nil {
+			t.Errorf("Expected an error for growth factor %v", growthFactor)
+		}
+	}
+}
+
+// nativeBuckets decodes the positive buckets of a native histogram by index.
+func nativeBuckets(histogram *dto.Histogram) map[int]int64 {
+	buckets := make(map[int]int64)
+	var index int32
+	var count int64
+	deltas := histogram.GetPositiveDelta()
+	for _, span := range histogram.GetPositiveSpan() {
+		index += span.GetOffset()
+		for i := uint32(0); i < span.GetLength(); i++ {
+			count += deltas[0]
+			deltas = deltas[1:]
+			if count != 0 {
+				buckets[int(index)] = count
+			}
+			index++
+		}
+	}
+	return buckets
+}
+
+func TestNativeHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name        string
+		exponential *monitoring.Exponential
+		schema      int32
+		counts      []int64
+		positive    map[int]int64
+		zeroCount   uint64
+	}{
+		{
+			// The bounds are 1, 2, 4 and 8, the overflow bucket goes to (8, 16]
+			name:        "exact",
+			exponential: &monitoring.Exponential{NumFiniteBuckets: 3, GrowthFactor: 2, Scale: 1},
+			schema:      0,
+			counts:      []int64{5, 1, 2, 3, 4},
+			positive:    map[int]int64{1: 1, 2: 2, 3: 3, 4: 4},
+			zeroCount:   5,
+		},
+		{
+			// The bounds are 1, 10 and 100 mapped to the buckets (1, 16], (16, 256] and (256, 4096]
+			name:        "approximate",
+			exponential: &monitoring.Exponential{NumFiniteBuckets: 2, GrowthFactor: 10, Scale: 1},
+			schema:      -2,
+			counts:      []int64{5, 1, 2, 4},
+			positive:    map[int]int64{1: 1, 2: 2, 3: 4},
+			zeroCount:   5,
+		},
+		{
+			name:        "empty underflow and overflow",
+			exponential: &monitoring.Exponential{NumFiniteBuckets: 3, GrowthFactor: 2, Scale: 1},
+			schema:      0,
+			counts:      []int64{0, 1, 2, 3, 0},
+			positive:    map[int]int64{1: 1, 2: 2, 3: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positive, zeroCount, zeroThreshold := nativeHistogramBuckets(tt.exponential, tt.counts, tt.schema)
+			if !maps.Equal(positive, tt.positive) {
+				t.Errorf("Expected buckets %v, got %v", tt.positive, positive)
+			}
+			if zeroCount != tt.zeroCount {
+				t.Errorf("Expected zero count %d, got %d", tt.zeroCount, zeroCount)
+			}
+			if zeroThreshold != tt.exponential.Scale {
+				t.Errorf("Expected zero threshold %v, got %v", tt.exponential.Scale, zeroThreshold)
+			}
+		})
+	}
+}
+
+func TestNativeHistograms(t *testing.T) {
+	metricType := "custom.googleapis.com/latency"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_latency"
+	descriptor := newTestDescriptor(metricType, "GAUGE", "DISTRIBUTION")
+
+	tests := []struct {
+		name          string
+		exponential   *monitoring.Exponential
+		bucketCounts  googleapi.Int64s
+		schema        int32
+		zeroThreshold float64
+		buckets       map[int]int64
+	}{
+		{
+			// The underflow, [1, 2), [2, 4), [4, 8) and overflow buckets
+			name:          "powers of 2",
+			exponential:   &monitoring.Exponential{Scale: 1, GrowthFactor: 2, NumFiniteBuckets: 3},
+			bucketCounts:  googleapi.Int64s{1, 2, 3, 0, 4},
+			schema:        0,
+			zeroThreshold: 1,
+			buckets:       map[int]int64{1: 2, 2: 3, 4: 4},
+		},
+		{
+			// The buckets of growth 10 are approximated by the buckets of growth 16
+			name:          "powers of 10",
+			exponential:   &monitoring.Exponential{Scale: 1, GrowthFactor: 10, NumFiniteBuckets: 2},
+			bucketCounts:  googleapi.Int64s{0, 5, 5},
+			schema:        -2,
+			zeroThreshold: 1,
+			buckets:       map[int]int64{1: 5, 2: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector, err := NewMonitoringCollector("test-project", &monitoring.Service{}, MonitoringCollectorOptions{
+				MetricTypePrefixes: []string{"custom.googleapis.com"},
+				RequestInterval:    5 * time.Minute,
+				NativeHistograms:   true,
+			}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+			if err != nil {
+				t.Fatalf("Failed to create collector: %v", err)
+			}
+
+			var count int64
+			for _, bucketCount := range tt.bucketCounts {
+				count += bucketCount
+			}
+			page := &monitoring.ListTimeSeriesResponse{
+				TimeSeries: []*monitoring.TimeSeries{
+					newTestDistributionTimeSeries(metricType, "GAUGE", &monitoring.Distribution{
+						Count:         count,
+						Mean:          2,
+						BucketCounts:  tt.bucketCounts,
+						BucketOptions: &monitoring.BucketOptions{ExponentialBuckets: tt.exponential},
+					}, time.Now()),
+				},
+			}
+
+			metrics := reportPage(t, collector, page, descriptor)[fqName]
+			if len(metrics) != 1 {
+				t.Fatalf("Expected 1 %s metric, got %d", fqName, len(metrics))
+			}
+			histogram := metrics[0].GetHistogram()
+			if histogram.GetSchema() != tt.schema {
+				t.Errorf("Expected schema %d, got %d", tt.schema, histogram.GetSchema())
+			}
+			if histogram.GetZeroThreshold() != tt.zeroThreshold || histogram.GetZeroCount() != uint64(tt.bucketCounts[0]) {
+				t.Errorf("Expected %d observations below %v, got %d below %v", tt.bucketCounts[0], tt.zeroThreshold, histogram.GetZeroCount(), histogram.GetZeroThreshold())
+			}
+			if got := nativeBuckets(histogram); !maps.Equal(got, tt.buckets) {
+				t.Errorf("Expected buckets %v, got %v", tt.buckets, got)
+			}
+			if histogram.GetSampleCount() != uint64(count) || histogram.GetSampleSum() != 2*float64(count) {
+				t.Errorf("Expected %d samples summing to %v, got %d summing to %v", count, 2*float64(count), histogram.GetSampleCount(), histogram.GetSampleSum())
+			}
+			if len(histogram.GetBucket()) != 0 {
+				t.Errorf("Expected no classic buckets, got %v", histogram.GetBucket())
+			}
+		})
+	}
+}
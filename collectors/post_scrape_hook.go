@@ -0,0 +1,34 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"time"
+)
+
+// ScrapeSummary describes what a scrape reported, it is passed to the post scrape hook of the collector.
+type ScrapeSummary struct {
+	ProjectID string
+	// Families is the number of metrics reported by metric family name, the internal metrics are not included.
+	Families map[string]int
+	// Metrics is the number of metrics reported, the internal metrics are not included.
+	Metrics     int
+	Descriptors int
+	Series      int
+	Samples     int
+	Errors      int
+	Duration    time.Duration
+	// Err is the error which failed the scrape, if any.
+	Err error
+}
@@ -0,0 +1,278 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// ProjectAggregationSum sums the identical series of all the projects.
+	ProjectAggregationSum = "sum"
+	// ProjectAggregationAvg averages the identical series of all the projects. Histograms are always summed.
+	ProjectAggregationAvg = "avg"
+
+	projectIDLabel = "project_id"
+)
+
+// ProjectAggregator is a collector which merges the identical series reported by the collectors of several projects
+// into a single series without the project_id label. The internal stackdriver_monitoring_* metrics are passed through
+// untouched.
+type ProjectAggregator struct {
+	gatherer              prometheus.Gatherer
+	aggregation           string
+	logger                *slog.Logger
+	bucketMismatchesTotal prometheus.Counter
+}
+
+// NewProjectAggregator returns a ProjectAggregator for the given per project collectors.
+func NewProjectAggregator(aggregation string, logger *slog.Logger, projectCollectors ...prometheus.Collector) (*ProjectAggregator, error) {
+	if aggregation != ProjectAggregationSum && aggregation != ProjectAggregationAvg {
+		return nil, fmt.Errorf("unknown project aggregation %q", aggregation)
+	}
+
+	registry := prometheus.NewRegistry()
+	for _, collector := range projectCollectors {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ProjectAggregator{
+		gatherer:    registry,
+		aggregation: aggregation,
+		logger:      logger,
+		bucketMismatchesTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "monitoring",
+				Name:      "project_aggregation_bucket_mismatches_total",
+				Help:      "Total number of histogram series not aggregated across projects because their bucket layouts differ.",
+			},
+		),
+	}, nil
+}
+
+// Describe is intentionally empty as the aggregated metrics are only known once collected.
+func (p *ProjectAggregator) Describe(ch chan<- *prometheus.Desc) {}
+
+func (p *ProjectAggregator) Collect(ch chan<- prometheus.Metric) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		p.logger.Error("error gathering metrics to aggregate across projects", "err", err)
+	}
+
+	internalPrefix := namespace + "_monitoring_"
+	for _, family := range families {
+		if strings.HasPrefix(family.GetName(), internalPrefix) {
+			for _, metric := range family.GetMetric() {
+				p.send(ch, family, []*dto.Metric{metric}, false)
+			}
+			continue
+		}
+
+		var order []string
+		groups := make(map[string][]*dto.Metric)
+		for _, metric := range family.GetMetric() {
+			key := aggregationKey(metric)
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], metric)
+		}
+
+		for _, key := range order {
+			p.send(ch, family, groups[key], true)
+		}
+	}
+
+	p.bucketMismatchesTotal.Collect(ch)
+}
+
+// aggregationKey identifies a series by all its labels except project_id.
+func aggregationKey(metric *dto.Metric) string {
+	var parts []string
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == projectIDLabel {
+			continue
+		}
+		parts = append(parts, label.GetName()+"="+label.GetValue())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
+
+// send merges the series of a group and sends the result. The project_id label is dropped from the aggregated
+// families, even when a single project reported the series, so that a series keeps the same labels whatever the number
+// of projects reporting it. The created timestamp of the result is the earliest of the group, if all have one.
+func (p *ProjectAggregator) send(ch chan<- prometheus.Metric, family *dto.MetricFamily, group []*dto.Metric, dropProjectID bool) {
+	first := group[0]
+	var labelKeys, labelValues []string
+	for _, label := range first.GetLabel() {
+		if dropProjectID && label.GetName() == projectIDLabel {
+			continue
+		}
+		labelKeys = append(labelKeys, label.GetName())
+		labelValues = append(labelValues, label.GetValue())
+	}
+
+	timestampMs := first.GetTimestampMs()
+	for _, other := range group[1:] {
+		if other.GetTimestampMs() > timestampMs {
+			timestampMs = other.GetTimestampMs()
+		}
+	}
+
+	desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), labelKeys, nil)
+
+	var metric prometheus.Metric
+	var err error
+	switch family.GetType() {
+	case dto.MetricType_COUNTER, dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		valueType := prometheus.UntypedValue
+		if family.GetType() == dto.MetricType_COUNTER {
+			valueType = prometheus.CounterValue
+		} else if family.GetType() == dto.MetricType_GAUGE {
+			valueType = prometheus.GaugeValue
+		}
+		var value float64
+		for _, m := range group {
+			value += m.GetCounter().GetValue() + m.GetGauge().GetValue() + m.GetUntyped().GetValue()
+		}
+		if p.aggregation == ProjectAggregationAvg {
+			value = value / float64(len(group))
+		}
+		createdTime := earliestCreatedTime(group, func(m *dto.Metric) *timestamppb.Timestamp { return m.GetCounter().GetCreatedTimestamp() })
+		if valueType == prometheus.CounterValue && !createdTime.IsZero() {
+			metric, err = prometheus.NewConstMetricWithCreatedTimestamp(desc, valueType, value, createdTime, labelValues...)
+		} else {
+			metric, err = prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+		}
+	case dto.MetricType_HISTOGRAM:
+		var merged bool
+		metric, merged, err = mergeHistograms(desc, group, labelValues)
+		if !merged {
+			p.bucketMismatchesTotal.Inc()
+			p.logger.Debug("discarding histogram with different bucket layouts across projects", "metric", family.GetName(), "labels", labelValues)
+			return
+		}
+	default:
+		p.logger.Debug("discarding unsupported metric type for project aggregation", "metric", family.GetName(), "type", family.GetType())
+		return
+	}
+	if err != nil {
+		p.logger.Error("error aggregating metric across projects", "metric", family.GetName(), "err", err)
+		return
+	}
+
+	if timestampMs != 0 {
+		metric = prometheus.NewMetricWithTimestamp(time.UnixMilli(timestampMs), metric)
+	}
+	ch <- metric
+}
+
+// mergeHistograms sums histograms which share the same bucket layout: the same bucket bounds for classic histograms,
+// the same schema and zero threshold for native histograms. It returns false when the layouts differ, as summing the
+// cumulative counts of different buckets does not produce a valid histogram. The classic buckets of a native histogram
+// are ignored.
+func mergeHistograms(desc *prometheus.Desc, group []*dto.Metric, labelValues []string) (prometheus.Metric, bool, error) {
+	first := group[0].GetHistogram()
+	var count uint64
+	var sum float64
+	for _, m := range group {
+		count += m.GetHistogram().GetSampleCount()
+		sum += m.GetHistogram().GetSampleSum()
+	}
+	createdTime := earliestCreatedTime(group, func(m *dto.Metric) *timestamppb.Timestamp { return m.GetHistogram().GetCreatedTimestamp() })
+
+	if first.Schema != nil {
+		var zeroCount uint64
+		positive, negative := make(map[int]int64), make(map[int]int64)
+		for _, m := range group {
+			h := m.GetHistogram()
+			if h.Schema == nil || h.GetSchema() != first.GetSchema() || h.GetZeroThreshold() != first.GetZeroThreshold() {
+				return nil, false, nil
+			}
+			zeroCount += h.GetZeroCount()
+			addNativeBuckets(positive, h.GetPositiveSpan(), h.GetPositiveDelta())
+			addNativeBuckets(negative, h.GetNegativeSpan(), h.GetNegativeDelta())
+		}
+		metric, err := prometheus.NewConstNativeHistogram(desc, count, sum, positive, negative, zeroCount, first.GetSchema(),
+			first.GetZeroThreshold(), createdTime, labelValues...)
+		return metric, true, err
+	}
+
+	buckets := make(map[float64]uint64, len(first.GetBucket()))
+	for _, m := range group {
+		h := m.GetHistogram()
+		if h.Schema != nil || len(h.GetBucket()) != len(first.GetBucket()) {
+			return nil, false, nil
+		}
+		for i, bucket := range h.GetBucket() {
+			if bucket.GetUpperBound() != first.GetBucket()[i].GetUpperBound() {
+				return nil, false, nil
+			}
+			buckets[bucket.GetUpperBound()] += bucket.GetCumulativeCount()
+		}
+	}
+	if createdTime.IsZero() {
+		metric, err := prometheus.NewConstHistogram(desc, count, sum, buckets, labelValues...)
+		return metric, true, err
+	}
+	metric, err := prometheus.NewConstHistogramWithCreatedTimestamp(desc, count, sum, buckets, createdTime, labelValues...)
+	return metric, true, err
+}
+
+// addNativeBuckets adds the counts of the buckets of a native histogram, encoded as spans and deltas, to buckets by
+// bucket index. The empty buckets filling the gaps between spans are skipped.
+func addNativeBuckets(buckets map[int]int64, spans []*dto.BucketSpan, deltas []int64) {
+	var index int32
+	var count int64
+	i := 0
+	for _, span := range spans {
+		index += span.GetOffset()
+		for j := uint32(0); j < span.GetLength() && i < len(deltas); j++ {
+			count += deltas[i]
+			if count != 0 {
+				buckets[int(index)] += count
+			}
+			index++
+			i++
+		}
+	}
+}
+
+// earliestCreatedTime returns the earliest created timestamp of the group, or the zero time if one of the series has
+// none. The timestamps before the epoch are treated as missing.
+func earliestCreatedTime(group []*dto.Metric, createdTimestamp func(*dto.Metric) *timestamppb.Timestamp) time.Time {
+	var earliest time.Time
+	for _, m := range group {
+		ts := createdTimestamp(m)
+		if ts.GetSeconds() <= 0 {
+			return time.Time{}
+		}
+		if t := ts.AsTime(); earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// staticCollector is an unchecked collector which reports the same metrics on every collect.
+type staticCollector struct {
+	metrics []prometheus.Metric
+}
+
+func (s *staticCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (s *staticCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range s.metrics {
+		ch <- m
+	}
+}
+
+func newStaticProjectCollector(projectID string, value float64) *staticCollector {
+	metricDesc := prometheus.NewDesc("stackdriver_gce_instance_custom_googleapis_com_requests", "Requests", []string{"unit", "zone", "project_id"}, nil)
+	internalDesc := prometheus.NewDesc("stackdriver_monitoring_scrapes_total", "Scrapes", nil, prometheus.Labels{"project_id": projectID})
+	return &staticCollector{
+		metrics: []prometheus.Metric{
+			prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, value, "1", "us-east1-b", projectID),
+			prometheus.MustNewConstMetric(internalDesc, prometheus.CounterValue, 1),
+		},
+	}
+}
+
+func TestProjectAggregator(t *testing.T) {
+	tests := []struct {
+		aggregation string
+		expected    float64
+	}{
+		{aggregation: ProjectAggregationSum, expected: 10},
+		{aggregation: ProjectAggregationAvg, expected: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			aggregator, err := NewProjectAggregator(tt.aggregation, slog.Default(),
+				newStaticProjectCollector("project-a", 4),
+				newStaticProjectCollector("project-b", 6),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create aggregator: %v", err)
+			}
+
+			families := gatherFamilies(t, aggregator)
+
+			metrics := families["stackdriver_gce_instance_custom_googleapis_com_requests"].GetMetric()
+			if len(metrics) != 1 {
+				t.Fatalf("Expected a single aggregated series, got %d", len(metrics))
+			}
+			if got := metrics[0].GetGauge().GetValue(); got != tt.expected {
+				t.Errorf("Expected aggregated value %v, got %v", tt.expected, got)
+			}
+			for _, label := range metrics[0].GetLabel() {
+				if label.GetName() == "project_id" {
+					t.Errorf("Expected project_id label to be dropped, got %q", label.GetValue())
+				}
+			}
+			if labelValue(metrics[0], "zone") != "us-east1-b" {
+				t.Errorf("Expected zone label to be kept, got %v", metrics[0].GetLabel())
+			}
+
+			if internal := families["stackdriver_monitoring_scrapes_total"].GetMetric(); len(internal) != 2 {
+				t.Errorf("Expected internal metrics to be kept per project, got %d series", len(internal))
+			}
+		})
+	}
+}
+
+func TestProjectAggregatorSingleProjectSeries(t *testing.T) {
+	projectA := newStaticProjectCollector("project-a", 4)
+	onlyDesc := prometheus.NewDesc("stackdriver_gce_instance_custom_googleapis_com_requests", "Requests", []string{"unit", "zone", "project_id"}, nil)
+	projectA.metrics = append(projectA.metrics, prometheus.MustNewConstMetric(onlyDesc, prometheus.GaugeValue, 3, "1", "us-west1-a", "project-a"))
+
+	aggregator, err := NewProjectAggregator(ProjectAggregationSum, slog.Default(), projectA, newStaticProjectCollector("project-b", 6))
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	metrics := gatherFamilies(t, aggregator)["stackdriver_gce_instance_custom_googleapis_com_requests"].GetMetric()
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(metrics))
+	}
+	for _, metric := range metrics {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "project_id" {
+				t.Errorf("Expected project_id label to be dropped from %v", metric.GetLabel())
+			}
+		}
+	}
+}
+
+func TestProjectAggregatorHistograms(t *testing.T) {
+	early, late := time.Unix(1700000000, 0), time.Unix(1700000600, 0)
+	classicDesc := prometheus.NewDesc("stackdriver_gce_instance_custom_googleapis_com_latency", "Latency", []string{"project_id"}, nil)
+	nativeDesc := prometheus.NewDesc("stackdriver_gce_instance_custom_googleapis_com_sizes", "Sizes", []string{"project_id"}, nil)
+	mismatchDesc := prometheus.NewDesc("stackdriver_gce_instance_custom_googleapis_com_delays", "Delays", []string{"project_id"}, nil)
+	newProject := func(projectID string, created time.Time, delayBounds []float64) *staticCollector {
+		delayBuckets := make(map[float64]uint64)
+		for _, bound := range delayBounds {
+			delayBuckets[bound] = 1
+		}
+		return &staticCollector{metrics: []prometheus.Metric{
+			prometheus.MustNewConstHistogramWithCreatedTimestamp(classicDesc, 3, 6, map[float64]uint64{1: 1, 2: 3}, created, projectID),
+			prometheus.MustNewConstNativeHistogram(nativeDesc, 3, 6, map[int]int64{1: 1, 3: 2}, nil, 0, 0, 0, time.Time{}, projectID),
+			prometheus.MustNewConstHistogram(mismatchDesc, 1, 1, delayBuckets, projectID),
+		}}
+	}
+
+	aggregator, err := NewProjectAggregator(ProjectAggregationAvg, slog.Default(),
+		newProject("project-a", late, []float64{1, 2}),
+		newProject("project-b", early, []float64{1, 5}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+	families := gatherFamilies(t, aggregator)
+
+	classic := families["stackdriver_gce_instance_custom_googleapis_com_latency"].GetMetric()
+	if len(classic) != 1 {
+		t.Fatalf("Expected a single classic histogram, got %d", len(classic))
+	}
+	histogram := classic[0].GetHistogram()
+	if histogram.GetSampleCount() != 6 || histogram.GetSampleSum() != 12 {
+		t.Errorf("Expected the classic histograms to be summed, got count %d and sum %v", histogram.GetSampleCount(), histogram.GetSampleSum())
+	}
+	for i, want := range []uint64{2, 6} {
+		if got := histogram.GetBucket()[i].GetCumulativeCount(); got != want {
+			t.Errorf("Expected bucket %d to be %d, got %d", i, want, got)
+		}
+	}
+	if got := histogram.GetCreatedTimestamp().AsTime(); !got.Equal(early) {
+		t.Errorf("Expected the earliest created timestamp %v, got %v", early, got)
+	}
+
+	native := families["stackdriver_gce_instance_custom_googleapis_com_sizes"].GetMetric()
+	if len(native) != 1 {
+		t.Fatalf("Expected a single native histogram, got %d", len(native))
+	}
+	buckets := make(map[int]int64)
+	addNativeBuckets(buckets, native[0].GetHistogram().GetPositiveSpan(), native[0].GetHistogram().GetPositiveDelta())
+	if len(buckets) != 2 || buckets[1] != 2 || buckets[3] != 4 {
+		t.Errorf("Expected the native histogram buckets to be summed, got %v", buckets)
+	}
+	if got := native[0].GetHistogram().GetSchema(); got != 0 {
+		t.Errorf("Expected schema 0, got %d", got)
+	}
+
+	if _, ok := families["stackdriver_gce_instance_custom_googleapis_com_delays"]; ok {
+		t.Error("Expected the histograms with different bucket bounds not to be aggregated")
+	}
+	if got := families["stackdriver_monitoring_project_aggregation_bucket_mismatches_total"].GetMetric()[0].GetCounter().GetValue(); got != 1 {
+		t.Errorf("Expected 1 bucket mismatch, got %v", got)
+	}
+}
+
+func TestProjectAggregatorInvalidAggregation(t *testing.T) {
+	if _, err := NewProjectAggregator("max", slog.Default()); err == nil {
+		t.Error("Expected an error for an unknown aggregation")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/utils"
+)
+
+// resourceLabelNames remaps the label keys of the monitored resources to names prefixed with the display name of
+// their resource descriptor, i.e. `zone` of a `gce_instance` ("VM Instance") to `vm_instance_zone`. The resource
+// descriptors are listed once; when they cannot be listed the raw keys are used and the listing is retried on the
+// next scrape. A nil resourceLabelNames keeps the raw keys.
+type resourceLabelNames struct {
+	service     *monitoring.Service
+	projectID   string
+	apiCalls    prometheus.Counter
+	inFlight    *inFlightCalls
+	logger      *slog.Logger
+	mu          sync.RWMutex
+	loaded      bool
+	displayKeys map[string]string
+}
+
+func newResourceLabelNames(service *monitoring.Service, projectID string, apiCalls prometheus.Counter, inFlight *inFlightCalls, logger *slog.Logger) *resourceLabelNames {
+	return &resourceLabelNames{
+		service:   service,
+		projectID: projectID,
+		apiCalls:  apiCalls,
+		inFlight:  inFlight,
+		logger:    logger,
+	}
+}
+
+// load lists the monitored resource descriptors unless they were already listed, and returns whether they were.
+func (r *resourceLabelNames) load() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return false
+	}
+
+	displayKeys := make(map[string]string)
+	done := r.inFlight.start()
+	err := r.service.Projects.MonitoredResourceDescriptors.List(utils.ProjectResource(r.projectID)).
+		Pages(context.Background(), func(page *monitoring.ListMonitoredResourceDescriptorsResponse) error {
+			r.apiCalls.Inc()
+			for _, descriptor := range page.ResourceDescriptors {
+				if displayKey := utils.NormalizeMetricName(descriptor.DisplayName); displayKey != "" {
+					displayKeys[descriptor.Type] = displayKey
+				}
+			}
+			return nil
+		})
+	done()
+	if err != nil {
+		r.logger.Error("error listing the monitored resource descriptors, using the raw resource label keys", "err", err)
+		return false
+	}
+	r.displayKeys = displayKeys
+	r.loaded = true
+	return true
+}
+
+// labelKey returns the label key of a resource label, the raw key if the resource type has no display name. The
+// project_id label is common to all the resources and is never remapped.
+func (r *resourceLabelNames) labelKey(resourceType, key string) string {
+	if r == nil || key == "project_id" {
+		return key
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if displayKey, ok := r.displayKeys[resourceType]; ok {
+		return displayKey + "_" + key
+	}
+	return key
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestResourceDisplayLabels(t *testing.T) {
+	metricType := "custom.googleapis.com/requests"
+	fqName := "stackdriver_gce_instance_custom_googleapis_com_requests"
+	timeSeries := newTestTimeSeries(metricType, "GAUGE", 1, time.Now())
+	timeSeries.Resource.Labels["zone"] = "europe-west1-b"
+
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+		resources: []*monitoring.MonitoredResourceDescriptor{
+			{Type: "gce_instance", DisplayName: "VM Instance"},
+		},
+		resourcesStatus: http.StatusForbidden,
+		timeSeries: map[string][]*monitoring.ListTimeSeriesResponse{
+			metricType: {{TimeSeries: []*monitoring.TimeSeries{timeSeries}}},
+		},
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes:    []string{"custom.googleapis.com"},
+		RequestInterval:       5 * time.Minute,
+		ResourceDisplayLabels: true,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	labelNames := func() []string {
+		family, ok := gatherFamilies(t, collector)[fqName]
+		if !ok {
+			t.Fatalf("Expected %s to be reported", fqName)
+		}
+		var names []string
+		for _, label := range family.GetMetric()[0].GetLabel() {
+			names = append(names, label.GetName())
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	// The raw keys are used while the resource descriptors cannot be listed
+	if expected, got := []string{"project_id", "unit", "zone"}, labelNames(); !slices.Equal(got, expected) {
+		t.Errorf("Expected the raw label keys %v, got %v", expected, got)
+	}
+
+	api.mu.Lock()
+	api.resourcesStatus = 0
+	api.mu.Unlock()
+
+	if expected, got := []string{"project_id", "unit", "vm_instance_zone"}, labelNames(); !slices.Equal(got, expected) {
+		t.Errorf("Expected the display label keys %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus-community/stackdriver_exporter/utils"
+)
+
+// ScrapeConfig is the aggregation and filter configuration of metric types loaded from a file. It is used along with
+// the MetricAggregationConfigs and ExtraFilters options, after them.
+type ScrapeConfig struct {
+	Aggregations []MetricAggregationConfig `yaml:"aggregations"`
+	ExtraFilters []MetricFilter            `yaml:"extra_filters"`
+}
+
+// emptyScrapeConfig is used when no scrape configuration file is set.
+var emptyScrapeConfig = &ScrapeConfig{}
+
+// LoadScrapeConfig reads and validates a scrape configuration file.
+func LoadScrapeConfig(path string) (*ScrapeConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ScrapeConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing scrape config %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scrape config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// validate checks the configuration and normalizes the alignment periods.
+func (s *ScrapeConfig) validate() error {
+	for i, aggregation := range s.Aggregations {
+		if aggregation.TargetedMetricPrefix == "" {
+			return fmt.Errorf("aggregation %d has no targeted metric prefix", i)
+		}
+		alignmentPeriod, err := utils.NormalizeAlignmentPeriod(aggregation.AlignmentPeriod)
+		if err != nil {
+			return fmt.Errorf("invalid aggregation for %s: %w", aggregation.TargetedMetricPrefix, err)
+		}
+		s.Aggregations[i].AlignmentPeriod = alignmentPeriod
+	}
+	for i, filter := range s.ExtraFilters {
+		if filter.TargetedMetricPrefix == "" || filter.FilterQuery == "" {
+			return fmt.Errorf("extra filter %d must have a targeted metric prefix and a filter query", i)
+		}
+	}
+	return nil
+}
+
+// ScrapeConfigFile is a scrape configuration file which can be reloaded while it is used by collectors. A scrape uses
+// the configuration loaded when it started until it completes.
+type ScrapeConfigFile struct {
+	path   string
+	config atomic.Pointer[ScrapeConfig]
+}
+
+// NewScrapeConfigFile loads a scrape configuration file.
+func NewScrapeConfigFile(path string) (*ScrapeConfigFile, error) {
+	f := &ScrapeConfigFile{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload loads the scrape configuration file again. The current configuration is kept if the file is invalid.
+func (f *ScrapeConfigFile) Reload() error {
+	config, err := LoadScrapeConfig(f.path)
+	if err != nil {
+		return err
+	}
+	f.config.Store(config)
+	return nil
+}
+
+// Config returns the current scrape configuration. A nil file has an empty configuration.
+func (f *ScrapeConfigFile) Config() *ScrapeConfig {
+	if f == nil {
+		return emptyScrapeConfig
+	}
+	return f.config.Load()
+}
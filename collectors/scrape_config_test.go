@@ -0,0 +1,172 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func writeScrapeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write scrape config: %v", err)
+	}
+}
+
+func TestLoadScrapeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scrape.yml")
+	writeScrapeConfig(t, path, `
+aggregations:
+  - targeted_metric_prefix: custom.googleapis.com/latency
+    alignment_period: 60
+    cross_series_reducer: REDUCE_SUM
+    group_by_fields: [metric.labels.instance_id]
+    per_series_aligner: ALIGN_DELTA
+extra_filters:
+  - targeted_metric_prefix: pubsub.googleapis.com/subscription
+    filter_query: resource.labels.subscription_id = "my-subscription"
+`)
+
+	config, err := LoadScrapeConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load scrape config: %v", err)
+	}
+
+	expected := &ScrapeConfig{
+		Aggregations: []MetricAggregationConfig{{
+			TargetedMetricPrefix: "custom.googleapis.com/latency",
+			AlignmentPeriod:      "60s",
+			CrossSeriesReducer:   "REDUCE_SUM",
+			GroupByFields:        []string{"metric.labels.instance_id"},
+			PerSeriesAligner:     "ALIGN_DELTA",
+		}},
+		ExtraFilters: []MetricFilter{{
+			TargetedMetricPrefix: "pubsub.googleapis.com/subscription",
+			FilterQuery:          `resource.labels.subscription_id = "my-subscription"`,
+		}},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("LoadScrapeConfig() = %+v, want %+v", config, expected)
+	}
+}
+
+func TestLoadScrapeConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "aggregation without prefix",
+			content: "aggregations:\n  - alignment_period: 60s\n",
+		},
+		{
+			name:    "invalid alignment period",
+			content: "aggregations:\n  - targeted_metric_prefix: custom.googleapis.com\n    alignment_period: 1m\n",
+		},
+		{
+			name:    "extra filter without query",
+			content: "extra_filters:\n  - targeted_metric_prefix: custom.googleapis.com\n",
+		},
+		{
+			name:    "unknown field",
+			content: "aggregations:\n  - targeted_metric_prefix: custom.googleapis.com\n    aligner: ALIGN_MEAN\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scrape.yml")
+			writeScrapeConfig(t, path, tt.content)
+			if _, err := LoadScrapeConfig(path); err == nil {
+				t.Error("Expected an error loading an invalid scrape config")
+			}
+		})
+	}
+
+	if _, err := NewScrapeConfigFile(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("Expected an error loading a missing scrape config")
+	}
+}
+
+func TestScrapeConfigReload(t *testing.T) {
+	metricType := "custom.googleapis.com/a"
+	api := &fakeMonitoringAPI{
+		descriptors: []*monitoring.MetricDescriptor{newTestDescriptor(metricType, "GAUGE", "DOUBLE")},
+	}
+	path := filepath.Join(t.TempDir(), "scrape.yml")
+	writeScrapeConfig(t, path, `
+aggregations:
+  - targeted_metric_prefix: custom.googleapis.com
+    alignment_period: 60s
+`)
+	file, err := NewScrapeConfigFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load scrape config: %v", err)
+	}
+
+	collector, err := NewMonitoringCollector("test-project", newFakeMonitoringService(t, api), MonitoringCollectorOptions{
+		MetricTypePrefixes: []string{"custom.googleapis.com"},
+		RequestInterval:    5 * time.Minute,
+		ScrapeConfigFile:   file,
+	}, slog.Default(), newTestCounterStore(), newTestHistogramStore())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	lastRequest := func() (alignmentPeriod, filter string) {
+		t.Helper()
+		gatherFamilies(t, collector)
+		request := api.timeSeriesRequests[len(api.timeSeriesRequests)-1]
+		return request.Get("aggregation.alignmentPeriod"), request.Get("filter")
+	}
+
+	if alignmentPeriod, _ := lastRequest(); alignmentPeriod != "60s" {
+		t.Errorf("Expected the loaded alignment period 60s, got %q", alignmentPeriod)
+	}
+
+	writeScrapeConfig(t, path, `
+aggregations:
+  - targeted_metric_prefix: custom.googleapis.com
+    alignment_period: 300s
+extra_filters:
+  - targeted_metric_prefix: custom.googleapis.com
+    filter_query: metric.labels.env = "prod"
+`)
+	if err := file.Reload(); err != nil {
+		t.Fatalf("Failed to reload scrape config: %v", err)
+	}
+	alignmentPeriod, filter := lastRequest()
+	if alignmentPeriod != "300s" {
+		t.Errorf("Expected the reloaded alignment period 300s, got %q", alignmentPeriod)
+	}
+	if !strings.Contains(filter, `metric.labels.env = "prod"`) {
+		t.Errorf("Expected the reloaded extra filter to be applied, got %q", filter)
+	}
+
+	writeScrapeConfig(t, path, "aggregations: invalid\n")
+	if err := file.Reload(); err == nil {
+		t.Error("Expected an error reloading an invalid scrape config")
+	}
+	if alignmentPeriod, _ := lastRequest(); alignmentPeriod != "300s" {
+		t.Errorf("Expected the previous configuration to be kept, got alignment period %q", alignmentPeriod)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// OverlappingScrapesConcurrent runs overlapping scrapes concurrently.
+	OverlappingScrapesConcurrent = "concurrent"
+	// OverlappingScrapesSerialize waits for the scrape in progress to complete before starting a new one.
+	OverlappingScrapesSerialize = "serialize"
+	// OverlappingScrapesReject does not start a new scrape while one is in progress and reports the metrics of the last
+	// complete scrape instead.
+	OverlappingScrapesReject = "reject"
+)
+
+// scrapeGuard detects the scrapes of a collector which overlap and applies the overlapping scrapes policy.
+type scrapeGuard struct {
+	policy   string
+	inFlight atomic.Int32
+	serial   sync.Mutex
+
+	mu   sync.Mutex
+	last []prometheus.Metric
+}
+
+// enter registers a scrape and returns whether another scrape is in progress. It must be followed by leave.
+func (g *scrapeGuard) enter() bool {
+	return g.inFlight.Add(1) > 1
+}
+
+func (g *scrapeGuard) leave() {
+	g.inFlight.Add(-1)
+}
+
+// record returns a channel forwarding the metrics to ch and a function keeping the forwarded metrics as the results of
+// the last scrape. The function must be called once nothing is sent to the channel anymore.
+func (g *scrapeGuard) record(ch chan<- prometheus.Metric) (chan<- prometheus.Metric, func()) {
+	recording := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		defer close(done)
+		for metric := range recording {
+			metrics = append(metrics, metric)
+			ch <- metric
+		}
+	}()
+
+	return recording, func() {
+		close(recording)
+		<-done
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.last = metrics
+	}
+}
+
+// replay sends the metrics of the last recorded scrape.
+func (g *scrapeGuard) replay(ch chan<- prometheus.Metric) {
+	g.mu.Lock()
+	last := g.last
+	g.mu.Unlock()
+	for _, metric := range last {
+		ch <- metric
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"maps"
+	"sync"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+// scrapeState holds what has been observed during a single scrape. It is shared by all the goroutines of the scrape,
+// a nil scrapeState ignores every observation.
+type scrapeState struct {
+	mu          sync.Mutex
+	metricTypes map[string]struct{}
+	descriptors map[string]*monitoring.MetricDescriptor
+	families    map[string]int
+	config      *ScrapeConfig
+	series      int
+	samples     int
+	errors      int
+	apiCalls    int
+}
+
+func newScrapeState(config *ScrapeConfig) *scrapeState {
+	return &scrapeState{
+		config:      config,
+		metricTypes: make(map[string]struct{}),
+		descriptors: make(map[string]*monitoring.MetricDescriptor),
+		families:    make(map[string]int),
+	}
+}
+
+// addMetricType records a metric type which reported at least one time series.
+func (s *scrapeState) addMetricType(metricType string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metricTypes[metricType] = struct{}{}
+}
+
+// metricTypesCount returns the number of distinct metric types which reported at least one time series.
+func (s *scrapeState) metricTypesCount() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.metricTypes)
+}
+
+// addSeries records the number of time series reported and of points read for a page of time series.
+func (s *scrapeState) addSeries(series, samples int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series += series
+	s.samples += samples
+}
+
+// addError records a failure to list the metric descriptors of a prefix or the time series of a descriptor.
+func (s *scrapeState) addError() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+}
+
+// addFamilies records the number of metrics reported by family name.
+func (s *scrapeState) addFamilies(families map[string]int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, count := range families {
+		s.families[name] += count
+	}
+}
+
+// familyCounts returns the number of recorded metrics by family name.
+func (s *scrapeState) familyCounts() map[string]int {
+	if s == nil {
+		return map[string]int{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.families)
+}
+
+// addAPICall records a Google Monitoring API call made by the scrape, the background refreshes are not recorded.
+func (s *scrapeState) addAPICall() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiCalls++
+}
+
+// apiCallsCount returns the number of recorded API calls.
+func (s *scrapeState) apiCallsCount() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiCalls
+}
+
+// counts returns the number of recorded descriptors, time series, points and errors.
+func (s *scrapeState) counts() (descriptors, series, samples, errors int) {
+	if s == nil {
+		return 0, 0, 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.descriptors), s.series, s.samples, s.errors
+}
+
+// addDescriptor records a metric descriptor whose time series are requested. A descriptor is recorded once per type.
+func (s *scrapeState) addDescriptor(descriptor *monitoring.MetricDescriptor) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.descriptors[descriptor.Type] = descriptor
+}
+
+// hasDescriptor returns whether a metric descriptor of the metric type has been recorded.
+func (s *scrapeState) hasDescriptor(metricType string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.descriptors[metricType]
+	return ok
+}
+
+// descriptorCounts returns the number of recorded metric descriptors by value type and by metric kind.
+func (s *scrapeState) descriptorCounts() (byValueType, byMetricKind map[string]int) {
+	byValueType, byMetricKind = make(map[string]int), make(map[string]int)
+	if s == nil {
+		return byValueType, byMetricKind
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, descriptor := range s.descriptors {
+		byValueType[descriptor.ValueType]++
+		byMetricKind[descriptor.MetricKind]++
+	}
+	return byValueType, byMetricKind
+}
+
+// scrapeConfig returns the scrape configuration used during the whole scrape.
+func (s *scrapeState) scrapeConfig() *ScrapeConfig {
+	if s == nil || s.config == nil {
+		return emptyScrapeConfig
+	}
+	return s.config
+}
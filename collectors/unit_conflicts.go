@@ -0,0 +1,75 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"google.golang.org/api/monitoring/v3"
+)
+
+const (
+	// UnitConflictsWarn logs the metric types whose descriptors report different units, once per metric type, and
+	// reports the unit of the last descriptor listed.
+	UnitConflictsWarn = "warn"
+	// UnitConflictsPick reports the lowest of the conflicting units, in lexical order, so that the unit label does not
+	// depend on the order in which the descriptors are listed.
+	UnitConflictsPick = "pick"
+	// UnitConflictsDrop reports an empty unit label for the metric types whose descriptors report different units.
+	UnitConflictsDrop = "drop"
+)
+
+// uniqueDescriptors keeps one descriptor per metric type and applies the unit conflicts policy to the metric types
+// whose descriptors, obtained from different projects, report different units. The conflicts are counted on every
+// scrape.
+func (c *MonitoringCollector) uniqueDescriptors(descriptors []*monitoring.MetricDescriptor) map[string]*monitoring.MetricDescriptor {
+	unique := make(map[string]*monitoring.MetricDescriptor, len(descriptors))
+	var conflicts map[string][]string
+	for _, descriptor := range descriptors {
+		retained, ok := unique[descriptor.Type]
+		if ok && retained.Unit != descriptor.Unit {
+			if conflicts == nil {
+				conflicts = make(map[string][]string)
+			}
+			if len(conflicts[descriptor.Type]) == 0 {
+				conflicts[descriptor.Type] = []string{retained.Unit}
+			}
+			conflicts[descriptor.Type] = append(conflicts[descriptor.Type], descriptor.Unit)
+			if c.unitConflicts == UnitConflictsPick && retained.Unit < descriptor.Unit {
+				continue
+			}
+		}
+		unique[descriptor.Type] = descriptor
+	}
+
+	for metricType, units := range conflicts {
+		c.unitConflictsTotalMetric.WithLabelValues(metricType).Inc()
+		switch c.unitConflicts {
+		case UnitConflictsWarn:
+			if _, warned := c.unitConflictsWarned.LoadOrStore(metricType, true); warned {
+				c.logger.Debug("metric descriptors of the same type report different units", "metric_type", metricType, "units", units, "unit", unique[metricType].Unit)
+				continue
+			}
+			c.logger.Warn("metric descriptors of the same type report different units", "metric_type", metricType, "units", units, "unit", unique[metricType].Unit)
+		case UnitConflictsPick:
+			c.logger.Debug("metric descriptors of the same type report different units", "metric_type", metricType, "units", units, "unit", unique[metricType].Unit)
+		case UnitConflictsDrop:
+			c.logger.Debug("metric descriptors of the same type report different units", "metric_type", metricType, "units", units)
+			// The descriptors may be cached, the unit is dropped from a copy.
+			descriptor := *unique[metricType]
+			descriptor.Unit = ""
+			unique[metricType] = &descriptor
+		}
+	}
+
+	return unique
+}
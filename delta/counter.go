@@ -68,6 +68,7 @@ func (s *InMemoryCounterStore) Increment(metricDescriptor *monitoring.MetricDesc
 
 	if existing == nil {
 		s.logger.Debug("Tracking new counter", "fqName", currentValue.FqName, "key", key, "current_value", currentValue.Value, "incoming_time", currentValue.ReportTime)
+		currentValue.CreatedTime = currentValue.CollectionTime
 		entry.Collected[key] = currentValue
 		return
 	}
@@ -75,6 +76,7 @@ func (s *InMemoryCounterStore) Increment(metricDescriptor *monitoring.MetricDesc
 	if existing.ReportTime.Before(currentValue.ReportTime) {
 		s.logger.Debug("Incrementing existing counter", "fqName", currentValue.FqName, "key", key, "current_value", existing.Value, "adding", currentValue.Value, "last_reported_time", existing.ReportTime, "incoming_time", currentValue.ReportTime)
 		currentValue.Value = currentValue.Value + existing.Value
+		currentValue.CreatedTime = existing.CreatedTime
 		entry.Collected[key] = currentValue
 		return
 	}
@@ -73,6 +73,51 @@ var _ = Describe("Counter", func() {
 		Expect(metrics[0].Value).To(Equal(float64(30)))
 	})
 
+	It("keeps the created time of a counter across increments", func() {
+		store.Increment(descriptor, metric)
+		createdTime := store.ListMetrics(descriptor.Name)[0].CreatedTime
+		Expect(createdTime).To(Equal(metric.CollectionTime))
+
+		metric2 := &collectors.ConstMetric{
+			FqName:         "counter_name",
+			LabelKeys:      []string{"labelKey"},
+			ValueType:      1,
+			Value:          20,
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     metric.ReportTime.Add(time.Second),
+			CollectionTime: metric.CollectionTime.Add(time.Minute),
+			KeysHash:       4321,
+		}
+		store.Increment(descriptor, metric2)
+
+		metrics := store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].CreatedTime).To(Equal(createdTime))
+	})
+
+	It("resets the created time of a counter once removed", func() {
+		metric.CollectionTime = metric.CollectionTime.Add(-time.Hour)
+		store.Increment(descriptor, metric)
+		Expect(len(store.ListMetrics(descriptor.Name))).To(Equal(0))
+
+		metric2 := &collectors.ConstMetric{
+			FqName:         "counter_name",
+			LabelKeys:      []string{"labelKey"},
+			ValueType:      1,
+			Value:          20,
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     time.Now().Truncate(time.Second),
+			CollectionTime: time.Now().Truncate(time.Second),
+			KeysHash:       4321,
+		}
+		store.Increment(descriptor, metric2)
+
+		metrics := store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].Value).To(Equal(float64(20)))
+		Expect(metrics[0].CreatedTime).To(Equal(metric2.CollectionTime))
+	})
+
 	It("will remove counters outside of TTL", func() {
 		metric.CollectionTime = metric.CollectionTime.Add(-time.Hour)
 
@@ -18,8 +18,11 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/PuerkitoBio/rehttp"
@@ -52,6 +55,10 @@ var (
 		"web.telemetry-path", "Path under which to expose Prometheus metrics.",
 	).Default("/metrics").String()
 
+	internalMetricsPath = kingpin.Flag(
+		"web.internal-telemetry-path", "Path under which to expose the internal stackdriver_monitoring_* metrics separately from the Stackdriver metrics.",
+	).Default("").String()
+
 	stackdriverMetricsPath = kingpin.Flag(
 		"web.stackdriver-telemetry-path", "Path under which to expose Stackdriver metrics.",
 	).Default("/metrics").String()
@@ -101,6 +108,22 @@ var (
 		"monitoring.metrics-prefixes", "Google Stackdriver Monitoring Metric Type prefixes. Repeat this flag to scrape multiple prefixes.",
 	).Strings()
 
+	monitoringMetricsTargets = kingpin.Flag(
+		"monitoring.metrics-targets", "Repeatable flag of metric types to scrape without listing their descriptors in the format: project_id:metric_type[:metric_kind:value_type[:alignment_period:cross_series_reducer:group_by_fields:per_series_aligner]]. Leave project_id empty to scrape all projects. Example: my-project:pubsub.googleapis.com/subscription/num_undelivered_messages",
+	).Strings()
+
+	monitoringDescriptorMaxSamplePeriod = kingpin.Flag(
+		"monitoring.descriptor-max-sample-period", "Only scrape the metric descriptors of the prefixes sampled at least this often. 0 scrapes all the descriptors",
+	).Default("0s").Duration()
+
+	monitoringDescriptorLaunchStages = kingpin.Flag(
+		"monitoring.descriptor-launch-stages", "Repeatable flag of launch stages, i.e. GA, of the metric descriptors of the prefixes to scrape. All the launch stages are scraped if not set",
+	).Strings()
+
+	monitoringMetricsAllowlistFile = kingpin.Flag(
+		"monitoring.metrics-allowlist-file", "File such as Prometheus rules or a dashboard definition referencing the stackdriver_* metrics to scrape. Only the metric descriptors of the prefixes reported with one of these names are scraped",
+	).Default("").String()
+
 	monitoringMetricsInterval = kingpin.Flag(
 		"monitoring.metrics-interval", "Interval to request the Google Stackdriver Monitoring Metrics for. Only the most recent data point is used.",
 	).Default("5m").Duration()
@@ -126,19 +149,80 @@ var (
 		"Filters. i.e: pubsub.googleapis.com/subscription:resource.labels.subscription_id=monitoring.regex.full_match(\"my-subs-prefix.*\")",
 	).Strings()
 
+	monitoringResourceLabelFilters = kingpin.Flag(
+		"monitoring.resource-label-filters",
+		"Repeatable flag of monitored resource labels the time series must match in the format: label=value. Example: env=prod",
+	).Strings()
+
 	monitoringMetricsWithAggregations = kingpin.Flag(
 		"monitoring.metrics-with-aggregations",
 		"Specify metrics with aggregation options in the format: metric_name:alignment_period:cross_series_reducer:group_by_fields:per_series_aligner. Example: custom.googleapis.com/my_metric:60s:REDUCE_SUM:metric.labels.instance_id,resource.labels.zone:ALIGN_MEAN",
 	).Strings()
 
+	monitoringScrapeConfigFile = kingpin.Flag(
+		"monitoring.scrape-config-file", "YAML file with additional aggregations and extra filters of metric types, reloaded on SIGHUP",
+	).Default("").String()
+
+	monitoringNameSuffixes = kingpin.Flag(
+		"monitoring.name-suffixes", "Append the conventional Prometheus suffixes to the metric names: the base unit (_bytes or _seconds) and _total for counters",
+	).Default("false").Bool()
+
+	monitoringValidatePrefixes = kingpin.Flag(
+		"monitoring.validate-prefixes", "Fail at startup if a metric type prefix matches no metric descriptor in a project",
+	).Default("false").Bool()
+
+	monitoringResourceDisplayLabels = kingpin.Flag(
+		"monitoring.resource-display-labels", "Prefix the monitored resource label keys with the display name of their resource descriptor, i.e. vm_instance_zone instead of zone",
+	).Default("false").Bool()
+
+	monitoringMaxPointsPerSeries = kingpin.Flag(
+		"monitoring.max-points-per-series", "Number of points per time series above which the alignment period of a metric type without aggregation is increased in the next scrapes. CUMULATIVE metrics and GAUGE distributions are never tuned. 0 disables it",
+	).Default("0").Int()
+
+	monitoringScrapeSummaryLog = kingpin.Flag(
+		"monitoring.scrape-summary-log", "Log a summary of every scrape at info level: prefixes, descriptors, time series, points, API calls, duration and errors",
+	).Default("false").Bool()
+
 	monitoringMetricsAggregateDeltas = kingpin.Flag(
 		"monitoring.aggregate-deltas", "If enabled will treat all DELTA metrics as an in-memory counter instead of a gauge",
 	).Default("false").Bool()
 
+	monitoringCreatedTimestamps = kingpin.Flag(
+		"monitoring.created-timestamps", "Report the counters accumulated in memory with the time they started being accumulated as created timestamp",
+	).Default("false").Bool()
+
+	monitoringMetricKindTypes = kingpin.Flag(
+		"monitoring.metric-kind-types", "Repeatable flag overriding the Prometheus type reported for a metric kind in the format: metric_kind[:aggregate_deltas]=counter|gauge|untyped|discard. DELTA metrics which are not aggregated cannot be counters. Example: GAUGE=untyped",
+	).Strings()
+
+	monitoringDeltaCounterPrefixes = kingpin.Flag(
+		"monitoring.delta-counter-prefixes", "Repeatable flag of metric type prefixes whose DELTA metrics are aggregated as counters even without monitoring.aggregate-deltas. Defaults to the log-based metrics prefix logging.googleapis.com/user, set it to an empty value to disable it",
+	).Strings()
+
 	monitoringMetricsDeltasTTL = kingpin.Flag(
 		"monitoring.aggregate-deltas-ttl", "How long should a delta metric continue to be exported after GCP stops producing a metric",
 	).Default("30m").Duration()
 
+	monitoringGaugeCounterPrefixes = kingpin.Flag(
+		"monitoring.gauge-counter-prefixes", "Repeatable flag of metric type prefixes of monotonic GAUGE metrics which should be reported as counters",
+	).Strings()
+
+	monitoringGaugeCounterTTL = kingpin.Flag(
+		"monitoring.gauge-counter-ttl", "How long should the previous value of a GAUGE metric reported as a counter be retained",
+	).Default("30m").Duration()
+
+	monitoringValueTypeAligners = kingpin.Flag(
+		"monitoring.value-type-aligners", "Repeatable flag overriding the per series aligner used by metric kind and value type when the matching metrics-with-aggregations has none, in the format: [metric_kind:]value_type=aligner. Without metric_kind the aligner applies to every metric kind. Example: DELTA:DISTRIBUTION=ALIGN_PERCENTILE_99",
+	).Strings()
+
+	monitoringMaxConcurrency = kingpin.Flag(
+		"monitoring.max-concurrency", "Maximum number of metric descriptors whose time series are requested concurrently per project, 0 for unlimited",
+	).Default("0").Int()
+
+	monitoringPrefixConcurrency = kingpin.Flag(
+		"monitoring.prefix-concurrency", "Repeatable flag capping the number of metric descriptors of a prefix whose time series are requested concurrently in the format: metric_prefix=limit. Example: compute.googleapis.com/instance=2",
+	).Strings()
+
 	monitoringDescriptorCacheTTL = kingpin.Flag(
 		"monitoring.descriptor-cache-ttl", "How long should the metric descriptors for a prefixed be cached for",
 	).Default("0s").Duration()
@@ -146,6 +230,78 @@ var (
 	monitoringDescriptorCacheOnlyGoogle = kingpin.Flag(
 		"monitoring.descriptor-cache-only-google", "Only cache descriptors for *.googleapis.com metrics",
 	).Default("true").Bool()
+
+	monitoringDescriptorCacheBackgroundRefresh = kingpin.Flag(
+		"monitoring.descriptor-cache-background-refresh", "Use expired cached descriptors while they are refreshed in the background",
+	).Default("false").Bool()
+
+	monitoringDescriptorScrapeErrors = kingpin.Flag(
+		"monitoring.descriptor-scrape-errors", "Report whether the last scrape of each metric descriptor resulted in an error",
+	).Default("false").Bool()
+
+	monitoringStrictExplicitBuckets = kingpin.Flag(
+		"monitoring.strict-explicit-buckets", "Discard DISTRIBUTION metrics with explicit buckets but no bounds instead of reporting a single +Inf bucket",
+	).Default("false").Bool()
+
+	monitoringLastSeenMetrics = kingpin.Flag(
+		"monitoring.last-seen-metrics", "Report a <metric>_last_seen_seconds gauge with the end time of the newest point of each time series",
+	).Default("false").Bool()
+
+	monitoringAPICallsLastScrape = kingpin.Flag(
+		"monitoring.api-calls-last-scrape", "Report the number of API calls made during the last scrape",
+	).Default("false").Bool()
+
+	monitoringDescriptorProfileMetrics = kingpin.Flag(
+		"monitoring.descriptor-profile-metrics", "Report the number of metric descriptors scraped in the last scrape by value type and by metric kind",
+	).Default("false").Bool()
+
+	monitoringSkipInvalidPoints = kingpin.Flag(
+		"monitoring.skip-invalid-points", "Skip and count the points whose end time cannot be parsed instead of failing the whole page of time series",
+	).Default("false").Bool()
+
+	monitoringConfigHashMetric = kingpin.Flag(
+		"monitoring.config-hash-metric", "Report a hash of the resolved scrape configuration to detect configuration drift",
+	).Default("false").Bool()
+
+	monitoringSkipMissingDescriptors = kingpin.Flag(
+		"monitoring.skip-missing-descriptors", "Skip and count the metric types deleted since their descriptor was listed instead of failing the scrape",
+	).Default("false").Bool()
+
+	monitoringOldestAPICallMetric = kingpin.Flag(
+		"monitoring.oldest-api-call-metric", "Report the age of the oldest API call in progress to detect hung calls",
+	).Default("false").Bool()
+
+	monitoringLabelLayoutCache = kingpin.Flag(
+		"monitoring.label-layout-cache", "Cache the resolved order of the label keys of the time series by metric type to skip the duplicate keys detection of the series with known label keys",
+	).Default("false").Bool()
+
+	monitoringNativeHistograms = kingpin.Flag(
+		"monitoring.native-histograms", "Report the exponential DISTRIBUTION metrics as native histograms with the schema closest to their growth factor. The underflow and overflow buckets are approximated by the zero bucket and the bucket following the last finite one. Native histograms are only exposed to protobuf scrapes, the text format only has their count and sum. Requires --no-collector.fill-missing-labels, the distributions whose missing labels are filled are reported as classic histograms",
+	).Default("false").Bool()
+
+	monitoringDistributionFallback = kingpin.Flag(
+		"monitoring.distribution-fallback", "Report the count and sum of DISTRIBUTION metrics as <metric>_count and <metric>_sum when no histogram can be generated from their buckets",
+	).Default("false").Bool()
+
+	monitoringFuturePoints = kingpin.Flag(
+		"monitoring.future-points", "How to handle a newest point with an end time in the future. One of: keep, drop, clamp",
+	).Default(collectors.FuturePointsKeep).Enum(collectors.FuturePointsKeep, collectors.FuturePointsDrop, collectors.FuturePointsClamp)
+
+	monitoringOverlappingScrapes = kingpin.Flag(
+		"monitoring.overlapping-scrapes", "What to do when a scrape starts while another one of the same project is in progress. One of: concurrent, serialize, reject (report the metrics of the last scrape)",
+	).Default(collectors.OverlappingScrapesConcurrent).Enum(collectors.OverlappingScrapesConcurrent, collectors.OverlappingScrapesSerialize, collectors.OverlappingScrapesReject)
+
+	monitoringNewestPointTies = kingpin.Flag(
+		"monitoring.newest-point-ties", "Which value to report when several points of a time series share the newest end time. One of: first, last, sum",
+	).Default(collectors.NewestPointFirst).Enum(collectors.NewestPointFirst, collectors.NewestPointLast, collectors.NewestPointSum)
+
+	monitoringUnitConflicts = kingpin.Flag(
+		"monitoring.unit-conflicts", "What to do when the metric descriptors of the same type, obtained from different projects, report different units. One of: warn (logged once per metric type), pick, drop",
+	).Default(collectors.UnitConflictsWarn).Enum(collectors.UnitConflictsWarn, collectors.UnitConflictsPick, collectors.UnitConflictsDrop)
+
+	monitoringAggregateProjects = kingpin.Flag(
+		"monitoring.aggregate-projects", "Aggregate the identical series of all the projects into a single series without the project_id label. One of: none, sum, avg",
+	).Default("none").Enum("none", collectors.ProjectAggregationSum, collectors.ProjectAggregationAvg)
 )
 
 func init() {
@@ -187,13 +343,22 @@ func createMonitoringService(ctx context.Context) (*monitoring.Service, error) {
 }
 
 type handler struct {
-	handler http.Handler
-	logger  *slog.Logger
+	handler         http.Handler
+	internalHandler http.Handler
+	logger          *slog.Logger
 
 	projectIDs                    []string
+	targetProjectIDs              []string
 	metricsPrefixes               []string
+	metricTargets                 []collectors.MetricTarget
 	metricsExtraFilters           []collectors.MetricFilter
+	resourceLabelFilters          map[string]string
 	metricsWithAggregationConfigs []collectors.MetricAggregationConfig
+	scrapeConfigFile              *collectors.ScrapeConfigFile
+	metricTypePolicy              collectors.MetricTypePolicy
+	prefixConcurrency             map[string]int
+	valueTypeAligners             map[string]string
+	metricNames                   []string
 	additionalGatherer            prometheus.Gatherer
 	m                             *monitoring.Service
 	collectors                    *collectors.CollectorCache
@@ -214,7 +379,7 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.handler.ServeHTTP(w, r)
 }
 
-func newHandler(projectIDs []string, metricPrefixes []string, metricExtraFilters []collectors.MetricFilter, metricsWithAggregationConfigs []collectors.MetricAggregationConfig, m *monitoring.Service, logger *slog.Logger, additionalGatherer prometheus.Gatherer) *handler {
+func newHandler(projectIDs []string, targetProjectIDs []string, metricPrefixes []string, metricTargets []collectors.MetricTarget, metricExtraFilters []collectors.MetricFilter, resourceLabelFilters map[string]string, metricsWithAggregationConfigs []collectors.MetricAggregationConfig, scrapeConfigFile *collectors.ScrapeConfigFile, m *monitoring.Service, logger *slog.Logger, additionalGatherer prometheus.Gatherer) *handler {
 	var ttl time.Duration
 	// Add collector caching TTL as max of deltas aggregation or descriptor caching
 	if *monitoringMetricsAggregateDeltas || *monitoringDescriptorCacheTTL > 0 {
@@ -231,38 +396,114 @@ func newHandler(projectIDs []string, metricPrefixes []string, metricExtraFilters
 	h := &handler{
 		logger:                        logger,
 		projectIDs:                    projectIDs,
+		targetProjectIDs:              targetProjectIDs,
 		metricsPrefixes:               metricPrefixes,
+		metricTargets:                 metricTargets,
 		metricsExtraFilters:           metricExtraFilters,
+		resourceLabelFilters:          resourceLabelFilters,
 		metricsWithAggregationConfigs: metricsWithAggregationConfigs,
+		scrapeConfigFile:              scrapeConfigFile,
 		additionalGatherer:            additionalGatherer,
 		m:                             m,
 		collectors:                    collectors.NewCollectorCache(ttl),
 	}
 
+	h.metricTypePolicy = parseMetricTypePolicy(logger, *monitoringMetricKindTypes)
+	h.prefixConcurrency = parsePrefixConcurrency(logger, *monitoringPrefixConcurrency)
+	h.valueTypeAligners = parseValueTypeAligners(logger, *monitoringValueTypeAligners)
+	if *monitoringMetricsAllowlistFile != "" {
+		metricNames, err := loadMetricNames(*monitoringMetricsAllowlistFile)
+		if err != nil {
+			logger.Error("error loading the metrics allowlist", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("Restricting the scraped metrics to the allowlist", "file", *monitoringMetricsAllowlistFile, "metrics", len(metricNames))
+		h.metricNames = metricNames
+	}
+
 	h.handler = h.innerHandler(nil)
+	if *internalMetricsPath != "" {
+		h.internalHandler = h.newInternalHandler()
+	}
 	return h
 }
 
 func (h *handler) getCollector(project string, filters map[string]bool) (*collectors.MonitoringCollector, error) {
 	filterdPrefixes := h.filterMetricTypePrefixes(filters)
-	collectorKey := fmt.Sprintf("%s-%v", project, filterdPrefixes)
+	filteredTargets := h.filterMetricTargets(filters)
+	if slices.Contains(h.targetProjectIDs, project) {
+		// A project only referenced by explicit targets is scraped for its own targets alone
+		filterdPrefixes = nil
+		var projectTargets []collectors.MetricTarget
+		for _, target := range filteredTargets {
+			if target.ProjectID == project {
+				projectTargets = append(projectTargets, target)
+			}
+		}
+		filteredTargets = projectTargets
+	}
+	// The prefixes filtered by a collect request are not validated, a typo must not stop the exporter. The collectors
+	// of a collect request are not part of the internal handler either, so they keep their internal metrics.
+	unfiltered := len(filters) == 0
+	collectorKey := fmt.Sprintf("%s-%v-%v-%v", project, filterdPrefixes, filteredTargets, unfiltered)
 
 	if collector, found := h.collectors.Get(collectorKey); found {
 		return collector, nil
 	}
 
+	descriptorPredicate := collectors.DescriptorPredicate{
+		MaxSamplePeriod: *monitoringDescriptorMaxSamplePeriod,
+		LaunchStages:    *monitoringDescriptorLaunchStages,
+		MetricNames:     h.metricNames,
+	}
+
 	collector, err := collectors.NewMonitoringCollector(project, h.m, collectors.MonitoringCollectorOptions{
-		MetricTypePrefixes:        filterdPrefixes,
-		ExtraFilters:              h.metricsExtraFilters,
-		MetricAggregationConfigs:  h.metricsWithAggregationConfigs,
-		RequestInterval:           *monitoringMetricsInterval,
-		RequestOffset:             *monitoringMetricsOffset,
-		IngestDelay:               *monitoringMetricsIngestDelay,
-		FillMissingLabels:         *collectorFillMissingLabels,
-		DropDelegatedProjects:     *monitoringDropDelegatedProjects,
-		AggregateDeltas:           *monitoringMetricsAggregateDeltas,
-		DescriptorCacheTTL:        *monitoringDescriptorCacheTTL,
-		DescriptorCacheOnlyGoogle: *monitoringDescriptorCacheOnlyGoogle,
+		MetricTypePrefixes:               filterdPrefixes,
+		ExplicitTargets:                  filteredTargets,
+		DescriptorPredicate:              descriptorPredicate,
+		ExtraFilters:                     h.metricsExtraFilters,
+		ResourceLabelFilters:             h.resourceLabelFilters,
+		MetricAggregationConfigs:         h.metricsWithAggregationConfigs,
+		ScrapeConfigFile:                 h.scrapeConfigFile,
+		RequestInterval:                  *monitoringMetricsInterval,
+		RequestOffset:                    *monitoringMetricsOffset,
+		IngestDelay:                      *monitoringMetricsIngestDelay,
+		FillMissingLabels:                *collectorFillMissingLabels,
+		DropDelegatedProjects:            *monitoringDropDelegatedProjects,
+		AggregateDeltas:                  *monitoringMetricsAggregateDeltas,
+		DeltaCounterPrefixes:             *monitoringDeltaCounterPrefixes,
+		MetricTypePolicy:                 h.metricTypePolicy,
+		CreatedTimestamps:                *monitoringCreatedTimestamps,
+		NameSuffixes:                     *monitoringNameSuffixes,
+		ScrapeSummaryLog:                 *monitoringScrapeSummaryLog,
+		MaxPointsPerSeries:               *monitoringMaxPointsPerSeries,
+		ResourceDisplayLabels:            *monitoringResourceDisplayLabels,
+		StartupValidatePrefixes:          *monitoringValidatePrefixes && unfiltered,
+		SeparateInternalMetrics:          *internalMetricsPath != "" && unfiltered,
+		DescriptorCacheTTL:               *monitoringDescriptorCacheTTL,
+		DescriptorCacheOnlyGoogle:        *monitoringDescriptorCacheOnlyGoogle,
+		DescriptorCacheBackgroundRefresh: *monitoringDescriptorCacheBackgroundRefresh,
+		DescriptorScrapeErrors:           *monitoringDescriptorScrapeErrors,
+		StrictExplicitBuckets:            *monitoringStrictExplicitBuckets,
+		LastSeenMetrics:                  *monitoringLastSeenMetrics,
+		APICallsLastScrape:               *monitoringAPICallsLastScrape,
+		DescriptorProfileMetrics:         *monitoringDescriptorProfileMetrics,
+		SkipInvalidPoints:                *monitoringSkipInvalidPoints,
+		ConfigHashMetric:                 *monitoringConfigHashMetric,
+		OldestAPICallMetric:              *monitoringOldestAPICallMetric,
+		SkipMissingDescriptors:           *monitoringSkipMissingDescriptors,
+		NativeHistograms:                 *monitoringNativeHistograms,
+		LabelLayoutCache:                 *monitoringLabelLayoutCache,
+		FuturePoints:                     *monitoringFuturePoints,
+		NewestPointTies:                  *monitoringNewestPointTies,
+		UnitConflicts:                    *monitoringUnitConflicts,
+		OverlappingScrapes:               *monitoringOverlappingScrapes,
+		DistributionFallback:             *monitoringDistributionFallback,
+		GaugeCounterPrefixes:             *monitoringGaugeCounterPrefixes,
+		GaugeCounterTTL:                  *monitoringGaugeCounterTTL,
+		MaxConcurrency:                   *monitoringMaxConcurrency,
+		PrefixConcurrency:                h.prefixConcurrency,
+		ValueTypeAligners:                h.valueTypeAligners,
 	}, h.logger, delta.NewInMemoryCounterStore(h.logger, *monitoringMetricsDeltasTTL), delta.NewInMemoryHistogramStore(h.logger, *monitoringMetricsDeltasTTL))
 	if err != nil {
 		return nil, err
@@ -274,13 +515,25 @@ func (h *handler) getCollector(project string, filters map[string]bool) (*collec
 func (h *handler) innerHandler(filters map[string]bool) http.Handler {
 	registry := prometheus.NewRegistry()
 
-	for _, project := range h.projectIDs {
+	var projectCollectors []prometheus.Collector
+	for _, project := range h.scrapedProjectIDs() {
 		monitoringCollector, err := h.getCollector(project, filters)
 		if err != nil {
 			h.logger.Error("error creating monitoring collector", "err", err)
 			os.Exit(1)
 		}
-		registry.MustRegister(monitoringCollector)
+		projectCollectors = append(projectCollectors, monitoringCollector)
+	}
+
+	if *monitoringAggregateProjects != "none" {
+		projectAggregator, err := collectors.NewProjectAggregator(*monitoringAggregateProjects, h.logger, projectCollectors...)
+		if err != nil {
+			h.logger.Error("error creating project aggregator", "err", err)
+			os.Exit(1)
+		}
+		registry.MustRegister(projectAggregator)
+	} else {
+		registry.MustRegister(projectCollectors...)
 	}
 	var gatherers prometheus.Gatherer = registry
 	if h.additionalGatherer != nil {
@@ -294,6 +547,41 @@ func (h *handler) innerHandler(filters map[string]bool) http.Handler {
 	return promhttp.HandlerFor(gatherers, opts)
 }
 
+// newInternalHandler returns the handler of the internal metrics of the collectors of all the projects.
+func (h *handler) newInternalHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+
+	for _, project := range h.scrapedProjectIDs() {
+		monitoringCollector, err := h.getCollector(project, nil)
+		if err != nil {
+			h.logger.Error("error creating monitoring collector", "err", err)
+			os.Exit(1)
+		}
+		registry.MustRegister(monitoringCollector.InternalCollector())
+	}
+
+	opts := promhttp.HandlerOpts{ErrorLog: slog.NewLogLogger(h.logger.Handler(), slog.LevelError)}
+	return promhttp.HandlerFor(registry, opts)
+}
+
+// scrapedProjectIDs returns the projects scraped for the metric type prefixes and explicit targets, followed by the
+// projects only scraped for their explicit targets.
+func (h *handler) scrapedProjectIDs() []string {
+	return append(slices.Clip(h.projectIDs), h.targetProjectIDs...)
+}
+
+// targetOnlyProjectIDs returns the projects of the explicit targets which are not part of the scraped projects.
+func targetOnlyProjectIDs(targets []collectors.MetricTarget, projectIDs []string) []string {
+	var targetProjectIDs []string
+	for _, target := range targets {
+		if target.ProjectID != "" && !slices.Contains(projectIDs, target.ProjectID) {
+			targetProjectIDs = append(targetProjectIDs, target.ProjectID)
+		}
+	}
+	slices.Sort(targetProjectIDs)
+	return slices.Compact(targetProjectIDs)
+}
+
 // filterMetricTypePrefixes filters the initial list of metric type prefixes, with the ones coming from an individual
 // prometheus collect request.
 func (h *handler) filterMetricTypePrefixes(filters map[string]bool) []string {
@@ -311,6 +599,24 @@ func (h *handler) filterMetricTypePrefixes(filters map[string]bool) []string {
 	return parseMetricTypePrefixes(filteredPrefixes)
 }
 
+// filterMetricTargets filters the explicit metric targets, with the ones matching the filters coming from an
+// individual prometheus collect request.
+func (h *handler) filterMetricTargets(filters map[string]bool) []collectors.MetricTarget {
+	if len(filters) == 0 {
+		return h.metricTargets
+	}
+	var filteredTargets []collectors.MetricTarget
+	for _, target := range h.metricTargets {
+		for filter := range filters {
+			if strings.HasPrefix(target.MetricType, filter) {
+				filteredTargets = append(filteredTargets, target)
+				break
+			}
+		}
+	}
+	return filteredTargets
+}
+
 func main() {
 	promslogConfig := &promslog.Config{}
 	flag.AddFlags(kingpin.CommandLine, promslogConfig)
@@ -326,8 +632,11 @@ func main() {
 	if *monitoringMetricsTypePrefixes != "" {
 		logger.Warn("The monitoring.metrics-type-prefixes flag is deprecated and will be replaced by monitoring.metrics-prefix.")
 	}
-	if *monitoringMetricsTypePrefixes == "" && len(*monitoringMetricsPrefixes) == 0 {
-		logger.Error("At least one GCP monitoring prefix is required.")
+	if *monitoringNativeHistograms && *collectorFillMissingLabels {
+		logger.Warn("The monitoring.native-histograms flag has no effect unless collector.fill-missing-labels is disabled.")
+	}
+	if *monitoringMetricsTypePrefixes == "" && len(*monitoringMetricsPrefixes) == 0 && len(*monitoringMetricsTargets) == 0 {
+		logger.Error("At least one GCP monitoring prefix or metrics target is required.")
 		os.Exit(1)
 	}
 
@@ -367,6 +676,12 @@ func main() {
 		discoveredProjectIDs = append(discoveredProjectIDs, strings.Split(*projectID, ",")...)
 	}
 
+	metricTargets, err := parseMetricTargets(logger, *monitoringMetricsTargets)
+	if err != nil {
+		logger.Error("Error parsing metrics targets", "err", err)
+		os.Exit(1)
+	}
+
 	var metricsPrefixes []string
 	if len(*monitoringMetricsPrefixes) > 0 {
 		metricsPrefixes = append(metricsPrefixes, *monitoringMetricsPrefixes...)
@@ -380,7 +695,9 @@ func main() {
 		"version", version.Info(),
 		"build_context", version.BuildContext(),
 		"metric_prefixes", fmt.Sprintf("%v", metricsPrefixes),
+		"metrics_targets", strings.Join(*monitoringMetricsTargets, ","),
 		"extra_filters", strings.Join(*monitoringMetricsExtraFilter, ","),
+		"resource_label_filters", strings.Join(*monitoringResourceLabelFilters, ","),
 		"aggregations", strings.Join(*monitoringMetricsWithAggregations, ","),
 		"projectIDs", fmt.Sprintf("%v", discoveredProjectIDs),
 		"projectsFilter", *projectsFilter,
@@ -388,22 +705,51 @@ func main() {
 
 	parsedMetricsPrefixes := parseMetricTypePrefixes(metricsPrefixes)
 	metricExtraFilters := parseMetricExtraFilters()
-	metricsWithAggregations := parseMetricsWithAggregations(logger, *monitoringMetricsWithAggregations)
+	resourceLabelFilters := parseResourceLabelFilters(logger, *monitoringResourceLabelFilters)
+	metricsWithAggregations, err := parseMetricsWithAggregations(logger, *monitoringMetricsWithAggregations)
+	if err != nil {
+		logger.Error("Error parsing metrics with aggregations", "err", err)
+		os.Exit(1)
+	}
 	// drop duplicate projects
 	slices.Sort(discoveredProjectIDs)
 	uniqueProjectIds := slices.Compact(discoveredProjectIDs)
+	targetProjectIDs := targetOnlyProjectIDs(metricTargets, uniqueProjectIds)
+	if len(targetProjectIDs) > 0 {
+		logger.Info("Scraping only the explicit targets of projects not otherwise scraped", "projectIDs", fmt.Sprintf("%v", targetProjectIDs))
+	}
 
+	if *internalMetricsPath != "" && (*internalMetricsPath == *metricsPath || *internalMetricsPath == *stackdriverMetricsPath) {
+		logger.Error("The internal metrics path must differ from the metrics and Stackdriver metrics paths.")
+		os.Exit(1)
+	}
+
+	var scrapeConfigFile *collectors.ScrapeConfigFile
+	if *monitoringScrapeConfigFile != "" {
+		scrapeConfigFile, err = collectors.NewScrapeConfigFile(*monitoringScrapeConfigFile)
+		if err != nil {
+			logger.Error("Error loading scrape config file", "err", err)
+			os.Exit(1)
+		}
+		go reloadScrapeConfigOnSignal(scrapeConfigFile, logger)
+	}
+
+	var stackdriverHandler *handler
 	if *metricsPath == *stackdriverMetricsPath {
-		handler := newHandler(
-			uniqueProjectIds, parsedMetricsPrefixes, metricExtraFilters, metricsWithAggregations, monitoringService, logger, prometheus.DefaultGatherer)
-		http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler))
+		stackdriverHandler = newHandler(
+			uniqueProjectIds, targetProjectIDs, parsedMetricsPrefixes, metricTargets, metricExtraFilters, resourceLabelFilters, metricsWithAggregations, scrapeConfigFile, monitoringService, logger, prometheus.DefaultGatherer)
+		http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, stackdriverHandler))
 	} else {
 		logger.Info("Serving Stackdriver metrics at separate path", "path", *stackdriverMetricsPath)
-		handler := newHandler(
-			uniqueProjectIds, parsedMetricsPrefixes, metricExtraFilters, metricsWithAggregations, monitoringService, logger, nil)
-		http.Handle(*stackdriverMetricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler))
+		stackdriverHandler = newHandler(
+			uniqueProjectIds, targetProjectIDs, parsedMetricsPrefixes, metricTargets, metricExtraFilters, resourceLabelFilters, metricsWithAggregations, scrapeConfigFile, monitoringService, logger, nil)
+		http.Handle(*stackdriverMetricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, stackdriverHandler))
 		http.Handle(*metricsPath, promhttp.Handler())
 	}
+	if stackdriverHandler.internalHandler != nil {
+		logger.Info("Serving internal metrics at separate path", "path", *internalMetricsPath)
+		http.Handle(*internalMetricsPath, stackdriverHandler.internalHandler)
+	}
 
 	if *metricsPath != "/" && *metricsPath != "" {
 		landingConfig := web.LandingConfig{
@@ -425,6 +771,14 @@ func main() {
 				},
 			)
 		}
+		if *internalMetricsPath != "" {
+			landingConfig.Links = append(landingConfig.Links,
+				web.LandingLinks{
+					Address: *internalMetricsPath,
+					Text:    "Internal Metrics",
+				},
+			)
+		}
 		landingPage, err := web.NewLandingPage(landingConfig)
 		if err != nil {
 			logger.Error("error creating landing page", "err", err)
@@ -440,6 +794,34 @@ func main() {
 	}
 }
 
+// reloadScrapeConfigOnSignal reloads the scrape config file on SIGHUP. An invalid file is logged and the previous
+// configuration is kept.
+func reloadScrapeConfigOnSignal(scrapeConfigFile *collectors.ScrapeConfigFile, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := scrapeConfigFile.Reload(); err != nil {
+			logger.Error("Error reloading scrape config file", "err", err)
+			continue
+		}
+		logger.Info("Reloaded scrape config file", "file", *monitoringScrapeConfigFile)
+	}
+}
+
+// loadMetricNames returns the metric names of the exporter referenced in a file. A file without any is an error, as
+// it would not restrict the scraped metrics.
+func loadMetricNames(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metricNames := collectors.ExtractMetricNames(content)
+	if len(metricNames) == 0 {
+		return nil, fmt.Errorf("no stackdriver metric name found in %s", path)
+	}
+	return metricNames, nil
+}
+
 func parseMetricTypePrefixes(inputPrefixes []string) []string {
 	metricTypePrefixes := []string{}
 
@@ -479,7 +861,67 @@ func parseMetricExtraFilters() []collectors.MetricFilter {
 	return extraFilters
 }
 
-func parseMetricsWithAggregations(logger *slog.Logger, input []string) []collectors.MetricAggregationConfig {
+func parseResourceLabelFilters(logger *slog.Logger, input []string) map[string]string {
+	filters := make(map[string]string)
+
+	for _, item := range input {
+		key, value := utils.SplitExtraFilter(item, "=")
+		if key == "" {
+			logger.Error("Invalid format for resource-label-filters", "filter", item)
+			continue
+		}
+		filters[key] = value
+	}
+
+	return filters
+}
+
+func parseValueTypeAligners(logger *slog.Logger, input []string) map[string]string {
+	aligners := collectors.DefaultValueTypeAligners()
+
+	for _, item := range input {
+		key, aligner := utils.SplitExtraFilter(item, "=")
+		metricKind, valueType, hasMetricKind := strings.Cut(key, ":")
+		if !hasMetricKind {
+			valueType = key
+		}
+		if valueType == "" || (hasMetricKind && metricKind == "") {
+			logger.Error("Invalid format for value-type-aligners", "aligner", item)
+			continue
+		}
+		if !hasMetricKind {
+			// An aligner by value type overrides the defaults of every metric kind
+			for k := range aligners {
+				if strings.HasSuffix(k, ":"+valueType) {
+					delete(aligners, k)
+				}
+			}
+		}
+		aligners[key] = aligner
+	}
+
+	return aligners
+}
+
+func parsePrefixConcurrency(logger *slog.Logger, input []string) map[string]int {
+	limits := make(map[string]int)
+
+	for _, item := range input {
+		prefix, value := utils.SplitExtraFilter(item, "=")
+		limit, err := strconv.Atoi(value)
+		if prefix == "" || err != nil || limit < 0 {
+			logger.Error("Invalid format for prefix-concurrency", "limit", item)
+			continue
+		}
+		limits[prefix] = limit
+	}
+
+	return limits
+}
+
+// parseMetricsWithAggregations parses the metrics-with-aggregations flags. Entries with an invalid format are skipped,
+// an invalid alignment period is an error as the metric would be scraped without aggregation.
+func parseMetricsWithAggregations(logger *slog.Logger, input []string) ([]collectors.MetricAggregationConfig, error) {
 	var configs []collectors.MetricAggregationConfig
 
 	for _, item := range input {
@@ -489,11 +931,16 @@ func parseMetricsWithAggregations(logger *slog.Logger, input []string) []collect
 			continue
 		}
 
+		alignmentPeriod, err := utils.NormalizeAlignmentPeriod(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid alignment period for metrics-with-aggregations %q: %w", item, err)
+		}
+
 		groupByFields := strings.Split(parts[3], ",")
 
 		config := collectors.MetricAggregationConfig{
 			TargetedMetricPrefix: parts[0],
-			AlignmentPeriod:      parts[1],
+			AlignmentPeriod:      alignmentPeriod,
 			CrossSeriesReducer:   parts[2],
 			GroupByFields:        groupByFields,
 			PerSeriesAligner:     parts[4],
@@ -501,5 +948,90 @@ func parseMetricsWithAggregations(logger *slog.Logger, input []string) []collect
 		configs = append(configs, config)
 	}
 
-	return configs
+	return configs, nil
+}
+
+// parseMetricTargets parses the metrics-targets flags. Targets with an invalid format are skipped, an invalid alignment
+// period is an error.
+func parseMetricTargets(logger *slog.Logger, input []string) ([]collectors.MetricTarget, error) {
+	var targets []collectors.MetricTarget
+
+	for _, item := range input {
+		parts := strings.Split(item, ":")
+		if (len(parts) != 2 && len(parts) != 4 && len(parts) != 8) || parts[1] == "" {
+			logger.Error("Invalid format for metrics-targets", "target", item)
+			continue
+		}
+
+		target := collectors.MetricTarget{
+			ProjectID:  parts[0],
+			MetricType: parts[1],
+		}
+		if len(parts) >= 4 {
+			target.MetricKind = parts[2]
+			target.ValueType = parts[3]
+		}
+		if len(parts) == 8 {
+			alignmentPeriod, err := utils.NormalizeAlignmentPeriod(parts[4])
+			if err != nil {
+				return nil, fmt.Errorf("invalid alignment period for metrics-targets %q: %w", item, err)
+			}
+			target.Aggregation = &collectors.MetricAggregationConfig{
+				TargetedMetricPrefix: parts[1],
+				AlignmentPeriod:      alignmentPeriod,
+				CrossSeriesReducer:   parts[5],
+				GroupByFields:        strings.Split(parts[6], ","),
+				PerSeriesAligner:     parts[7],
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func parseMetricTypePolicy(logger *slog.Logger, input []string) collectors.MetricTypePolicy {
+	if len(input) == 0 {
+		return nil
+	}
+
+	policy := collectors.DefaultMetricTypePolicy()
+	for _, item := range input {
+		key, prometheusType := utils.SplitExtraFilter(item, "=")
+		metricKind, aggregateDeltas, hasAggregateDeltas := strings.Cut(key, ":")
+		if metricKind == "" || (hasAggregateDeltas && aggregateDeltas != "true" && aggregateDeltas != "false") {
+			logger.Error("Invalid format for metric-kind-types", "type", item)
+			continue
+		}
+
+		keys := []collectors.MetricTypeKey{{MetricKind: metricKind, AggregateDeltas: aggregateDeltas == "true"}}
+		if !hasAggregateDeltas {
+			keys = []collectors.MetricTypeKey{
+				{MetricKind: metricKind, AggregateDeltas: false},
+				{MetricKind: metricKind, AggregateDeltas: true},
+			}
+		}
+
+		for _, k := range keys {
+			switch prometheusType {
+			case "counter":
+				if k.MetricKind == "DELTA" && !k.AggregateDeltas {
+					// The raw values of DELTA metrics go up and down, rate() would be meaningless on a counter
+					logger.Error("Counter type is invalid for DELTA metrics which are not aggregated", "type", item)
+					continue
+				}
+				policy[k] = prometheus.CounterValue
+			case "gauge":
+				policy[k] = prometheus.GaugeValue
+			case "untyped":
+				policy[k] = prometheus.UntypedValue
+			case "discard":
+				delete(policy, k)
+			default:
+				logger.Error("Invalid type for metric-kind-types", "type", item)
+			}
+		}
+	}
+
+	return policy
 }
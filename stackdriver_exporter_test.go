@@ -14,10 +14,16 @@
 package main
 
 import (
+	"io"
 	"log/slog"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+
 	"github.com/prometheus-community/stackdriver_exporter/collectors"
 )
 
@@ -162,6 +168,21 @@ func TestParseMetricsWithAggregations(t *testing.T) {
 			},
 			expected: []collectors.MetricAggregationConfig{},
 		},
+		{
+			name: "numeric only alignment period is normalized",
+			input: []string{
+				"custom.googleapis.com/my_metric:60:REDUCE_SUM:metric.labels.instance_id:ALIGN_MEAN",
+			},
+			expected: []collectors.MetricAggregationConfig{
+				{
+					TargetedMetricPrefix: "custom.googleapis.com/my_metric",
+					AlignmentPeriod:      "60s",
+					CrossSeriesReducer:   "REDUCE_SUM",
+					GroupByFields:        []string{"metric.labels.instance_id"},
+					PerSeriesAligner:     "ALIGN_MEAN",
+				},
+			},
+		},
 		{
 			name: "mixed valid and invalid configs",
 			input: []string{
@@ -190,7 +211,10 @@ func TestParseMetricsWithAggregations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parseMetricsWithAggregations(logger, tt.input)
+			result, err := parseMetricsWithAggregations(logger, tt.input)
+			if err != nil {
+				t.Fatalf("parseMetricsWithAggregations() returned error %v", err)
+			}
 
 			// For empty expected results, check length instead of using reflect.DeepEqual
 			if len(tt.expected) == 0 {
@@ -205,3 +229,259 @@ func TestParseMetricsWithAggregations(t *testing.T) {
 		})
 	}
 }
+
+func TestParseResourceLabelFilters(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name     string
+		input    []string
+		expected map[string]string
+	}{
+		{
+			name:     "valid filters",
+			input:    []string{"env=prod", "zone=us-east1-b"},
+			expected: map[string]string{"env": "prod", "zone": "us-east1-b"},
+		},
+		{
+			name:     "value containing separator",
+			input:    []string{"selector=a=b"},
+			expected: map[string]string{"selector": "a=b"},
+		},
+		{
+			name:     "invalid filters are skipped",
+			input:    []string{"env=prod", "invalid", "=value"},
+			expected: map[string]string{"env": "prod"},
+		},
+		{
+			name:     "empty input",
+			input:    []string{},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseResourceLabelFilters(logger, tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseResourceLabelFilters() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseValueTypeAligners(t *testing.T) {
+	logger := slog.Default()
+
+	if aligners := parseValueTypeAligners(logger, nil); !reflect.DeepEqual(aligners, collectors.DefaultValueTypeAligners()) {
+		t.Errorf("Expected the default aligners without overrides, got %v", aligners)
+	}
+
+	aligners := parseValueTypeAligners(logger, []string{"DISTRIBUTION=ALIGN_PERCENTILE_99", "GAUGE:INT64=", "CUMULATIVE:DOUBLE=ALIGN_RATE", "invalid", "=ALIGN_SUM", ":DOUBLE=ALIGN_SUM", "DELTA:=ALIGN_SUM"})
+	expected := collectors.DefaultValueTypeAligners()
+	delete(expected, "DELTA:DISTRIBUTION")
+	expected["DISTRIBUTION"] = "ALIGN_PERCENTILE_99"
+	expected["GAUGE:INT64"] = ""
+	expected["CUMULATIVE:DOUBLE"] = "ALIGN_RATE"
+	if !reflect.DeepEqual(aligners, expected) {
+		t.Errorf("parseValueTypeAligners() = %v, want %v", aligners, expected)
+	}
+}
+
+func TestParsePrefixConcurrency(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name     string
+		input    []string
+		expected map[string]int
+	}{
+		{
+			name:     "valid limits",
+			input:    []string{"compute.googleapis.com/instance=2", "custom.googleapis.com=1"},
+			expected: map[string]int{"compute.googleapis.com/instance": 2, "custom.googleapis.com": 1},
+		},
+		{
+			name:     "invalid limits are skipped",
+			input:    []string{"custom.googleapis.com=1", "invalid", "=2", "pubsub.googleapis.com=many", "redis.googleapis.com=-1"},
+			expected: map[string]int{"custom.googleapis.com": 1},
+		},
+		{
+			name:     "empty input",
+			input:    []string{},
+			expected: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parsePrefixConcurrency(logger, tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parsePrefixConcurrency() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMetricTargets(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name     string
+		input    []string
+		expected []collectors.MetricTarget
+	}{
+		{
+			name:  "project and metric type",
+			input: []string{"my-project:pubsub.googleapis.com/subscription/num_undelivered_messages"},
+			expected: []collectors.MetricTarget{
+				{ProjectID: "my-project", MetricType: "pubsub.googleapis.com/subscription/num_undelivered_messages"},
+			},
+		},
+		{
+			name:  "all projects with kind and value type",
+			input: []string{":custom.googleapis.com/my_metric:CUMULATIVE:INT64"},
+			expected: []collectors.MetricTarget{
+				{MetricType: "custom.googleapis.com/my_metric", MetricKind: "CUMULATIVE", ValueType: "INT64"},
+			},
+		},
+		{
+			name:  "with aggregation",
+			input: []string{"my-project:custom.googleapis.com/my_metric:GAUGE:DOUBLE:60s:REDUCE_SUM:resource.labels.zone:ALIGN_MEAN"},
+			expected: []collectors.MetricTarget{
+				{
+					ProjectID:  "my-project",
+					MetricType: "custom.googleapis.com/my_metric",
+					MetricKind: "GAUGE",
+					ValueType:  "DOUBLE",
+					Aggregation: &collectors.MetricAggregationConfig{
+						TargetedMetricPrefix: "custom.googleapis.com/my_metric",
+						AlignmentPeriod:      "60s",
+						CrossSeriesReducer:   "REDUCE_SUM",
+						GroupByFields:        []string{"resource.labels.zone"},
+						PerSeriesAligner:     "ALIGN_MEAN",
+					},
+				},
+			},
+		},
+		{
+			name:  "invalid targets are skipped",
+			input: []string{"my-project", "my-project:", "my-project:custom.googleapis.com/my_metric:GAUGE", "my-project:custom.googleapis.com/ok"},
+			expected: []collectors.MetricTarget{
+				{ProjectID: "my-project", MetricType: "custom.googleapis.com/ok"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMetricTargets(logger, tt.input)
+			if err != nil {
+				t.Fatalf("parseMetricTargets() returned error %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseMetricTargets() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseInvalidAlignmentPeriod(t *testing.T) {
+	logger := slog.Default()
+
+	if _, err := parseMetricsWithAggregations(logger, []string{
+		"custom.googleapis.com/ok:60s:REDUCE_SUM:metric.labels.instance_id:ALIGN_MEAN",
+		"custom.googleapis.com/my_metric:1m:REDUCE_SUM:metric.labels.instance_id:ALIGN_MEAN",
+	}); err == nil {
+		t.Error("Expected an error for an invalid metrics-with-aggregations alignment period")
+	}
+	if _, err := parseMetricTargets(logger, []string{
+		"my-project:custom.googleapis.com/my_metric:GAUGE:DOUBLE:1m:REDUCE_SUM:resource.labels.zone:ALIGN_MEAN",
+	}); err == nil {
+		t.Error("Expected an error for an invalid metrics-targets alignment period")
+	}
+}
+
+func TestTargetOnlyProjectIDs(t *testing.T) {
+	targets := []collectors.MetricTarget{
+		{ProjectID: "project-b", MetricType: "custom.googleapis.com/a"},
+		{MetricType: "custom.googleapis.com/b"},
+		{ProjectID: "project-a", MetricType: "custom.googleapis.com/c"},
+		{ProjectID: "project-c", MetricType: "custom.googleapis.com/d"},
+		{ProjectID: "project-b", MetricType: "custom.googleapis.com/e"},
+	}
+
+	result := targetOnlyProjectIDs(targets, []string{"project-a"})
+	if expected := []string{"project-b", "project-c"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("targetOnlyProjectIDs() = %v, want %v", result, expected)
+	}
+}
+
+func TestParseMetricTypePolicy(t *testing.T) {
+	logger := slog.Default()
+
+	if policy := parseMetricTypePolicy(logger, nil); policy != nil {
+		t.Errorf("Expected no policy without overrides, got %v", policy)
+	}
+
+	policy := parseMetricTypePolicy(logger, []string{
+		"DELTA:false=counter",
+		"DELTA=counter",
+		"GAUGE=untyped",
+		"CUMULATIVE:true=discard",
+		"CUMULATIVE:maybe=gauge",
+		"CUMULATIVE=histogram",
+		"=gauge",
+	})
+
+	expected := collectors.DefaultMetricTypePolicy()
+	expected[collectors.MetricTypeKey{MetricKind: "GAUGE", AggregateDeltas: false}] = prometheus.UntypedValue
+	expected[collectors.MetricTypeKey{MetricKind: "GAUGE", AggregateDeltas: true}] = prometheus.UntypedValue
+	delete(expected, collectors.MetricTypeKey{MetricKind: "CUMULATIVE", AggregateDeltas: true})
+
+	if !reflect.DeepEqual(policy, expected) {
+		t.Errorf("parseMetricTypePolicy() = %v, want %v", policy, expected)
+	}
+}
+
+func TestGetCollectorFilteredInternalMetrics(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{"--web.internal-telemetry-path=/internal"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	t.Cleanup(func() { *internalMetricsPath = "" })
+
+	prefixes := []string{"custom.googleapis.com"}
+	h := newHandler([]string{"test-project"}, nil, prefixes, nil, nil, nil, nil, nil, &monitoring.Service{}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	unfiltered, err := h.getCollector("test-project", nil)
+	if err != nil {
+		t.Fatalf("Failed to get the unfiltered collector: %v", err)
+	}
+	// A collect request for all the configured prefixes must not reuse the collector with separate internal metrics
+	filtered, err := h.getCollector("test-project", map[string]bool{"custom.googleapis.com": true})
+	if err != nil {
+		t.Fatalf("Failed to get the filtered collector: %v", err)
+	}
+	if filtered == unfiltered {
+		t.Fatal("Expected the collect request to get its own collector")
+	}
+
+	describesInternalMetrics := func(collector prometheus.Collector) bool {
+		ch := make(chan *prometheus.Desc)
+		go func() {
+			collector.Describe(ch)
+			close(ch)
+		}()
+		var found bool
+		for desc := range ch {
+			found = found || strings.Contains(desc.String(), `"stackdriver_monitoring_scrapes_total"`)
+		}
+		return found
+	}
+	if describesInternalMetrics(unfiltered) {
+		t.Error("Expected the unfiltered collector to separate its internal metrics")
+	}
+	if !describesInternalMetrics(filtered) {
+		t.Error("Expected the filtered collector to keep its internal metrics")
+	}
+}
@@ -15,6 +15,7 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -23,7 +24,8 @@ import (
 )
 
 var (
-	safeNameRE = regexp.MustCompile(`[^a-zA-Z0-9_]*$`)
+	safeNameRE        = regexp.MustCompile(`[^a-zA-Z0-9_]*$`)
+	durationSecondsRE = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,9})?$`)
 )
 
 func NormalizeMetricName(metricName string) string {
@@ -49,6 +51,21 @@ func SplitExtraFilter(extraFilter string, separator string) (string, string) {
 	return mPrefix[0], mPrefix[1]
 }
 
+// NormalizeAlignmentPeriod validates an aggregation alignment period as a Google API duration, i.e. "60s". A number of
+// seconds without unit is normalized by appending "s". An empty alignment period is kept as is.
+func NormalizeAlignmentPeriod(alignmentPeriod string) (string, error) {
+	if alignmentPeriod == "" {
+		return "", nil
+	}
+	if durationSecondsRE.MatchString(alignmentPeriod) {
+		return alignmentPeriod + "s", nil
+	}
+	if strings.HasSuffix(alignmentPeriod, "s") && durationSecondsRE.MatchString(strings.TrimSuffix(alignmentPeriod, "s")) {
+		return alignmentPeriod, nil
+	}
+	return "", fmt.Errorf("invalid alignment period %q, expected a number of seconds such as \"60s\"", alignmentPeriod)
+}
+
 func ProjectResource(projectID string) string {
 	return "projects/" + projectID
 }
@@ -32,6 +32,37 @@ var _ = Describe("ProjectResource", func() {
 	})
 })
 
+var _ = Describe("NormalizeAlignmentPeriod", func() {
+	It("keeps a valid alignment period", func() {
+		alignmentPeriod, err := NormalizeAlignmentPeriod("60s")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alignmentPeriod).To(Equal("60s"))
+
+		alignmentPeriod, err = NormalizeAlignmentPeriod("1.5s")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alignmentPeriod).To(Equal("1.5s"))
+	})
+
+	It("keeps an empty alignment period", func() {
+		alignmentPeriod, err := NormalizeAlignmentPeriod("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alignmentPeriod).To(Equal(""))
+	})
+
+	It("appends the unit to a numeric only alignment period", func() {
+		alignmentPeriod, err := NormalizeAlignmentPeriod("60")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alignmentPeriod).To(Equal("60s"))
+	})
+
+	It("returns an error for an invalid alignment period", func() {
+		for _, invalid := range []string{"1m", "60 s", "-60s", "s", "sixty"} {
+			_, err := NormalizeAlignmentPeriod(invalid)
+			Expect(err).To(HaveOccurred(), invalid)
+		}
+	})
+})
+
 var _ = Describe("SplitExtraFilter", func() {
 	It("returns an empty string from incomplete filter", func() {
 		metricPrefix, filterQuery := SplitExtraFilter("This_is__a-MetricName.Example/with/no/filter", ":")